@@ -0,0 +1,101 @@
+package ddblocal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// args returns the command line arguments for the java runtime launching
+// DynamoDB Local.
+func (e *Emulator) args() []string {
+	args := append([]string{}, e.jvmOptions...)
+	if e.heapSize != "" {
+		args = append(args, "-Xmx"+e.heapSize)
+	}
+	args = append(args, e.customJVMOptions...)
+	args = append(
+		args,
+		fmt.Sprintf("-Djava.library.path=%s", e.libPath),
+		"-jar",
+		e.jarPath,
+		"-port",
+		strconv.Itoa(e.port),
+	)
+	if !e.nonSharedDB {
+		args = append(args, "-sharedDb")
+	}
+	if e.dbPath != "" {
+		args = append(args, "-dbPath", e.dbPath)
+	} else {
+		args = append(args, "-inMemory")
+	}
+	if e.delayTransientStatuses {
+		args = append(args, "-delayTransientStatuses")
+	}
+	if e.optimizeDBBeforeStartup {
+		args = append(args, "-optimizeDbBeforeStartup")
+	}
+	if len(e.corsOrigins) > 0 {
+		args = append(args, "-cors", strings.Join(e.corsOrigins, ","))
+	}
+	if e.disableTelemetry {
+		args = append(args, "-disableTelemetry")
+	}
+	return args
+}
+
+// validate checks the configuration of the DynamoDB Local process for
+// incompatible combinations of options.
+func (e *Emulator) validate() error {
+	if e.inMemory && e.dbPath != "" {
+		return errors.New("incompatible options: -inMemory and -dbPath")
+	}
+	if e.optimizeDBBeforeStartup && e.dbPath == "" {
+		return errors.New("incompatible options: -optimizeDbBeforeStartup requires -dbPath")
+	}
+	if e.container && e.dbPath != "" {
+		return errors.New("incompatible options: -dbPath is not supported by the container backend")
+	}
+	if e.endpoint != "" {
+		switch {
+		case e.shareDir != "":
+			return errors.New("incompatible options: CustomEndpoint and Shared")
+		case e.container:
+			return errors.New("incompatible options: CustomEndpoint and ContainerBackend")
+		case e.reusePolicy == AlwaysStartFresh:
+			return errors.New("incompatible options: CustomEndpoint and the always-start-fresh reuse policy")
+		}
+		// the port-based interfaces would be used with localhost instead of
+		// the host of the endpoint
+		if _, ok := e.pc.(proberAdapter); ok {
+			return errors.New("incompatible options: CustomEndpoint and CustomPresenceChecker, use CustomProber instead")
+		}
+		if _, ok := e.ci.(clientInitializerAdapter); ok {
+			return errors.New("incompatible options: CustomEndpoint and CustomClientInitializer, use CustomClientInitializerContext instead")
+		}
+	}
+	if e.shareDir != "" {
+		switch {
+		case e.reusePolicy != ReuseIfPresent:
+			return fmt.Errorf("incompatible options: Shared and the %s reuse policy", e.reusePolicy)
+		case e.port == 0:
+			// every process would pick a different port
+			return errors.New("incompatible options: Shared and RandomPort")
+		case e.container:
+			// the recorded pid would be the one of the container CLI, and
+			// killing it doesn't remove the container
+			return errors.New("incompatible options: Shared and ContainerBackend")
+		}
+	}
+	if !validTableNamePrefix(e.tableNamePrefix) {
+		return fmt.Errorf("invalid table name prefix: %q", e.tableNamePrefix)
+	}
+	for _, origin := range e.corsOrigins {
+		if origin == "" || strings.Contains(origin, ",") {
+			return fmt.Errorf("invalid -cors origin: %q", origin)
+		}
+	}
+	return nil
+}
@@ -1,7 +1,8 @@
 package ddblocal
 
 import (
-	"fmt"
+	"context"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -10,29 +11,56 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
-type clientInitializer func(port int) (dynamodbiface.DynamoDBAPI, error)
+type clientInitializer func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error)
 
 func (c clientInitializer) InitClient(port int) (dynamodbiface.DynamoDBAPI, error) {
-	return c(port)
+	return c(context.Background(), localEndpoint(port))
 }
 
-func initClient(port int) (dynamodbiface.DynamoDBAPI, error) {
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Endpoint:    aws.String(fmt.Sprintf("http://localhost:%d", port)),
-			Credentials: credentials.NewStaticCredentials("test", "test", ""),
-			Region:      aws.String("test"),
-		},
-	})
-	if err != nil {
-		return nil, err
+func (c clientInitializer) InitClientContext(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+	return c(ctx, endpoint)
+}
+
+// sessionMu serializes the creation of sessions, which modifies global state
+// in the SDK, so that emulators can be started concurrently.
+var sessionMu sync.Mutex
+
+const (
+	defaultRegion          = "test"
+	defaultAccessKeyID     = "test"
+	defaultSecretAccessKey = "test"
+)
+
+// initClient returns a function initializing clients which use the given
+// region and static credentials.
+func initClient(region, accessKeyID, secretAccessKey string) func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+	return func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		sessionMu.Lock()
+		defer sessionMu.Unlock()
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config: aws.Config{
+				Endpoint:    aws.String(endpoint),
+				Credentials: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
+				Region:      aws.String(region),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		client := dynamodb.New(sess)
+		return client, nil
 	}
-	client := dynamodb.New(sess)
-	return client, nil
 }
 
 // NewClientInitialier returns a new instance of ClientInitializer with default
 // configuration.
 func NewClientInitialier() ClientInitializer {
-	return clientInitializer(initClient)
+	return newClientInitializer(defaultRegion, defaultAccessKeyID, defaultSecretAccessKey)
+}
+
+func newClientInitializer(region, accessKeyID, secretAccessKey string) clientInitializer {
+	return clientInitializer(initClient(region, accessKeyID, secretAccessKey))
 }
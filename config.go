@@ -0,0 +1,534 @@
+package ddblocal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the part of the Emulator configuration which can be provided by
+// environment variables and config files. Empty fields are left at their
+// defaults, while booleans which are set, including to false, override the
+// defaults and the sources with lower precedence. Durations are written like
+// "30s" and the reuse policy like "require-existing".
+//
+// Every field can be set with the DDBLOCAL_ environment variable named after
+// its upper-cased key, e.g. DDBLOCAL_PORT or DDBLOCAL_REUSE_POLICY, except for
+// the JVM options which are read from DDBLOCAL_JVM_OPTS and the jar and lib
+// paths which are read from DDBLOCAL_JAR and DDBLOCAL_LIB. Lists are separated
+// by spaces in DDBLOCAL_JVM_OPTS and by commas in DDBLOCAL_CORS.
+type Config struct {
+	Port        *int   `json:"port,omitempty" yaml:"port,omitempty"`
+	Endpoint    string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	ReusePolicy string `json:"reuse_policy,omitempty" yaml:"reuse_policy,omitempty"`
+
+	Region          string `json:"region,omitempty" yaml:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+
+	JavaPath       string   `json:"java_path,omitempty" yaml:"java_path,omitempty"`
+	MinJavaVersion int      `json:"min_java_version,omitempty" yaml:"min_java_version,omitempty"`
+	JVMOptions     []string `json:"jvm_options,omitempty" yaml:"jvm_options,omitempty"`
+	HeapSize       string   `json:"heap_size,omitempty" yaml:"heap_size,omitempty"`
+	JarPath        string   `json:"jar,omitempty" yaml:"jar,omitempty"`
+	LibPath        string   `json:"lib,omitempty" yaml:"lib,omitempty"`
+	Install        *bool    `json:"install,omitempty" yaml:"install,omitempty"`
+
+	DBPath                  string   `json:"db_path,omitempty" yaml:"db_path,omitempty"`
+	InMemory                *bool    `json:"in_memory,omitempty" yaml:"in_memory,omitempty"`
+	NonSharedDB             *bool    `json:"non_shared_db,omitempty" yaml:"non_shared_db,omitempty"`
+	DelayTransientStatuses  *bool    `json:"delay_transient_statuses,omitempty" yaml:"delay_transient_statuses,omitempty"`
+	OptimizeDBBeforeStartup *bool    `json:"optimize_db_before_startup,omitempty" yaml:"optimize_db_before_startup,omitempty"`
+	CORS                    []string `json:"cors,omitempty" yaml:"cors,omitempty"`
+	DisableTelemetry        *bool    `json:"disable_telemetry,omitempty" yaml:"disable_telemetry,omitempty"`
+
+	StartupTimeout    string `json:"startup_timeout,omitempty" yaml:"startup_timeout,omitempty"`
+	StartupBackoff    string `json:"startup_backoff,omitempty" yaml:"startup_backoff,omitempty"`
+	StartupMaxBackoff string `json:"startup_max_backoff,omitempty" yaml:"startup_max_backoff,omitempty"`
+	TableTimeout      string `json:"table_timeout,omitempty" yaml:"table_timeout,omitempty"`
+	GracePeriod       string `json:"grace_period,omitempty" yaml:"grace_period,omitempty"`
+	Restarts          int    `json:"restarts,omitempty" yaml:"restarts,omitempty"`
+
+	Container      *bool  `json:"container,omitempty" yaml:"container,omitempty"`
+	ContainerCLI   string `json:"container_cli,omitempty" yaml:"container_cli,omitempty"`
+	ContainerImage string `json:"container_image,omitempty" yaml:"container_image,omitempty"`
+
+	TableNamePrefix  string `json:"table_name_prefix,omitempty" yaml:"table_name_prefix,omitempty"`
+	KeepFailedTables *bool  `json:"keep_failed_tables,omitempty" yaml:"keep_failed_tables,omitempty"`
+	SkipUnavailable  *bool  `json:"skip_unavailable,omitempty" yaml:"skip_unavailable,omitempty"`
+
+	Shared   *bool  `json:"shared,omitempty" yaml:"shared,omitempty"`
+	ShareDir string `json:"share_dir,omitempty" yaml:"share_dir,omitempty"`
+	PidDir   string `json:"pid_dir,omitempty" yaml:"pid_dir,omitempty"`
+}
+
+// String returns the configuration in YAML, with the secret access key
+// redacted.
+func (c Config) String() string {
+	if c.SecretAccessKey != "" {
+		c.SecretAccessKey = "REDACTED"
+	}
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// options translates the configuration into options.
+func (c Config) options() ([]EmulatorOption, error) {
+	var options []EmulatorOption
+	add := func(option EmulatorOption) {
+		options = append(options, option)
+	}
+	// applies an explicit false as well, so that it overrides true from
+	// another source
+	flag := func(v *bool, set func(e *Emulator, b bool)) {
+		if v != nil {
+			b := *v
+			add(func(e *Emulator) {
+				set(e, b)
+			})
+		}
+	}
+
+	if c.Port != nil {
+		add(CustomPort(*c.Port))
+	}
+	if c.Endpoint != "" {
+		add(CustomEndpoint(c.Endpoint))
+	}
+	if c.ReusePolicy != "" {
+		policy, err := ParseReusePolicy(c.ReusePolicy)
+		if err != nil {
+			return nil, err
+		}
+		add(CustomReusePolicy(policy))
+	}
+
+	if c.Region != "" {
+		add(CustomRegion(c.Region))
+	}
+	// either key alone leaves the other one as it is
+	if c.AccessKeyID != "" {
+		accessKeyID := c.AccessKeyID
+		add(func(e *Emulator) {
+			e.accessKeyID = accessKeyID
+		})
+	}
+	if c.SecretAccessKey != "" {
+		secretAccessKey := c.SecretAccessKey
+		add(func(e *Emulator) {
+			e.secretAccessKey = secretAccessKey
+		})
+	}
+
+	if c.JavaPath != "" {
+		add(CustomJavaPath(c.JavaPath))
+	}
+	if c.MinJavaVersion != 0 {
+		add(CustomMinJavaVersion(c.MinJavaVersion))
+	}
+	if c.JVMOptions != nil {
+		// replaces rather than extends the options from other sources
+		jvmOptions := c.JVMOptions
+		add(func(e *Emulator) {
+			e.jvmOptions = jvmOptions
+		})
+	}
+	if c.HeapSize != "" {
+		add(CustomHeapSize(c.HeapSize))
+	}
+	if c.JarPath != "" {
+		add(CustomJarPath(c.JarPath))
+	}
+	if c.LibPath != "" {
+		add(CustomLibPath(c.LibPath))
+	}
+	flag(c.Install, func(e *Emulator, b bool) {
+		e.inst = nil
+		if b {
+			e.inst = NewInstaller()
+		}
+	})
+
+	if c.DBPath != "" {
+		add(CustomDBPath(c.DBPath))
+	}
+	flag(c.InMemory, func(e *Emulator, b bool) { e.inMemory = b })
+	flag(c.NonSharedDB, func(e *Emulator, b bool) { e.nonSharedDB = b })
+	flag(c.DelayTransientStatuses, func(e *Emulator, b bool) { e.delayTransientStatuses = b })
+	flag(c.OptimizeDBBeforeStartup, func(e *Emulator, b bool) { e.optimizeDBBeforeStartup = b })
+	if c.CORS != nil {
+		add(CustomCORS(c.CORS...))
+	}
+	flag(c.DisableTelemetry, func(e *Emulator, b bool) { e.disableTelemetry = b })
+
+	durations := []struct {
+		name  string
+		value string
+		set   func(e *Emulator, d time.Duration)
+	}{
+		{"startup_timeout", c.StartupTimeout, func(e *Emulator, d time.Duration) { e.startupTimeout = d }},
+		{"startup_backoff", c.StartupBackoff, func(e *Emulator, d time.Duration) { e.startupBackoff = d }},
+		{"startup_max_backoff", c.StartupMaxBackoff, func(e *Emulator, d time.Duration) { e.startupMaxBackoff = d }},
+		{"table_timeout", c.TableTimeout, func(e *Emulator, d time.Duration) { e.tableTimeout = d }},
+		{"grace_period", c.GracePeriod, func(e *Emulator, d time.Duration) { e.gracePeriod = d }},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", duration.name, err)
+		}
+		set := duration.set
+		add(func(e *Emulator) {
+			set(e, d)
+		})
+	}
+	if c.Restarts != 0 {
+		add(CustomRestarts(c.Restarts))
+	}
+
+	flag(c.Container, func(e *Emulator, b bool) { e.container = b })
+	if c.ContainerCLI != "" {
+		add(CustomContainerCLI(c.ContainerCLI))
+	}
+	if c.ContainerImage != "" {
+		add(CustomContainerImage(c.ContainerImage))
+	}
+
+	if c.TableNamePrefix != "" {
+		add(CustomTableNamePrefix(c.TableNamePrefix))
+	}
+	flag(c.KeepFailedTables, func(e *Emulator, b bool) { e.keepFailedTables = b })
+	flag(c.SkipUnavailable, func(e *Emulator, b bool) { e.skipUnavailable = b })
+
+	flag(c.Shared, func(e *Emulator, b bool) {
+		e.shareDir = ""
+		if b {
+			Shared()(e)
+		}
+	})
+	// an explicit false from the same source wins over the directory
+	if c.ShareDir != "" && (c.Shared == nil || *c.Shared) {
+		add(CustomShareDir(c.ShareDir))
+	}
+	if c.PidDir != "" {
+		add(CustomPidDir(c.PidDir))
+	}
+	return options, nil
+}
+
+// Config returns the effective configuration of the Emulator.
+func (e *Emulator) Config() Config {
+	port := e.port
+	c := Config{
+		Port:        &port,
+		Endpoint:    e.endpoint,
+		ReusePolicy: e.reusePolicy.String(),
+
+		Region:          e.region,
+		AccessKeyID:     e.accessKeyID,
+		SecretAccessKey: e.secretAccessKey,
+
+		JavaPath:       e.javaPath,
+		MinJavaVersion: e.minJavaVersion,
+		JVMOptions:     append(append([]string{}, e.jvmOptions...), e.customJVMOptions...),
+		HeapSize:       e.heapSize,
+		JarPath:        e.jarPath,
+		LibPath:        e.libPath,
+		Install:        boolPtr(e.inst != nil),
+
+		DBPath:                  e.dbPath,
+		InMemory:                boolPtr(e.inMemory),
+		NonSharedDB:             boolPtr(e.nonSharedDB),
+		DelayTransientStatuses:  boolPtr(e.delayTransientStatuses),
+		OptimizeDBBeforeStartup: boolPtr(e.optimizeDBBeforeStartup),
+		CORS:                    e.corsOrigins,
+		DisableTelemetry:        boolPtr(e.disableTelemetry),
+
+		StartupTimeout:    e.startupTimeout.String(),
+		StartupBackoff:    e.startupBackoff.String(),
+		StartupMaxBackoff: e.startupMaxBackoff.String(),
+		TableTimeout:      e.tableTimeout.String(),
+		GracePeriod:       e.gracePeriod.String(),
+		Restarts:          e.restarts,
+
+		Container:      boolPtr(e.container),
+		ContainerCLI:   e.containerCLI,
+		ContainerImage: e.containerImage,
+
+		TableNamePrefix:  e.tableNamePrefix,
+		KeepFailedTables: boolPtr(e.keepFailedTables),
+		SkipUnavailable:  boolPtr(e.skipUnavailable),
+
+		Shared:   boolPtr(e.shareDir != ""),
+		ShareDir: e.shareDir,
+		PidDir:   e.pidDir,
+	}
+	if len(c.JVMOptions) == 0 {
+		c.JVMOptions = nil
+	}
+	return c
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// EffectiveConfig returns the configuration an Emulator created with the
+// given options would have, without starting anything.
+func EffectiveConfig(options ...EmulatorOption) (Config, error) {
+	e, err := configure(options)
+	if err != nil {
+		return Config{}, err
+	}
+	return e.Config(), nil
+}
+
+// configure returns an Emulator with the default configuration overridden by
+// the config file, the environment and the options, in this order.
+func configure(options []EmulatorOption) (*Emulator, error) {
+	// default Emulator configuration
+	ddb := &Emulator{
+		pc:   newPresenceChecker(),
+		tng:  NewStringGenerator(),
+		port: 8000,
+
+		region:          defaultRegion,
+		accessKeyID:     defaultAccessKeyID,
+		secretAccessKey: defaultSecretAccessKey,
+
+		startupTimeout:    30 * time.Second,
+		startupBackoff:    50 * time.Millisecond,
+		startupMaxBackoff: time.Second,
+		tableTimeout:      defaultTableTimeout,
+
+		logs: newLogBuffer(logBufferSize),
+
+		gracePeriod: defaultGracePeriod,
+
+		minJavaVersion: defaultMinJavaVersion,
+
+		pidDir: filepath.Join(os.TempDir(), "ddblocal", "pids"),
+
+		containerCLI:   defaultContainerCLI,
+		containerImage: defaultContainerImage,
+	}
+
+	fileConfig, configFile, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	envConfig, err := configFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range []Config{fileConfig, envConfig} {
+		configOptions, err := c.options()
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range configOptions {
+			option(ddb)
+		}
+	}
+
+	// apply option overrides
+	for _, option := range options {
+		option(ddb)
+	}
+
+	if ddb.ci == nil {
+		ddb.ci = newClientInitializer(ddb.region, ddb.accessKeyID, ddb.secretAccessKey)
+	}
+
+	// a config file found in a parent directory may come as a surprise
+	if configFile != "" {
+		msg := fmt.Sprintf("ddblocal: using config file %s\n", configFile)
+		_, _ = io.WriteString(ddb.logs, msg)
+		if ddb.logWriter != nil {
+			_, _ = io.WriteString(ddb.logWriter, msg)
+		}
+	}
+
+	if err := ddb.validate(); err != nil {
+		return nil, err
+	}
+	return ddb, nil
+}
+
+// configFileNames are the names of the config files looked for in the working
+// directory and its parents, up to the root of the module or repository.
+var configFileNames = []string{"ddblocal.yaml", "ddblocal.yml", "ddblocal.json"}
+
+// loadConfigFile loads the config file named by the DDBLOCAL_CONFIG
+// environment variable or, if it is not set, the first one found in the
+// working directory or its parents, and returns its path.
+func loadConfigFile() (Config, string, error) {
+	path := os.Getenv("DDBLOCAL_CONFIG")
+	if path == "" {
+		var err error
+		if path, err = findConfigFile(); err != nil || path == "" {
+			return Config{}, "", err
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	var c Config
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&c)
+	} else {
+		err = yaml.UnmarshalStrict(data, &c)
+	}
+	if err != nil {
+		return Config{}, "", fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return c, path, nil
+}
+
+// findConfigFile returns the path of the config file closest to the working
+// directory, or an empty string if there is none. The search stops at the
+// first directory holding a go.mod file or a .git directory, so that a config
+// file outside of the module or repository isn't picked up.
+func findConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		if isProjectRoot(dir) {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// isProjectRoot reports whether dir is the root of a module or repository.
+func isProjectRoot(dir string) bool {
+	for _, name := range []string{"go.mod", ".git"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// configFromEnv reads the configuration from the DDBLOCAL_ environment
+// variables.
+func configFromEnv() (Config, error) {
+	var c Config
+	var errs []string
+
+	str := func(name string, dst *string) {
+		*dst = os.Getenv("DDBLOCAL_" + name)
+	}
+	integer := func(name string, dst *int) bool {
+		v := os.Getenv("DDBLOCAL_" + name)
+		if v == "" {
+			return false
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid DDBLOCAL_%s: %q", name, v))
+			return false
+		}
+		*dst = i
+		return true
+	}
+	boolean := func(name string, dst **bool) {
+		v := os.Getenv("DDBLOCAL_" + name)
+		if v == "" {
+			return
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid DDBLOCAL_%s: %q", name, v))
+			return
+		}
+		*dst = &b
+	}
+
+	var port int
+	if integer("PORT", &port) {
+		c.Port = &port
+	}
+	str("ENDPOINT", &c.Endpoint)
+	str("REUSE_POLICY", &c.ReusePolicy)
+
+	str("REGION", &c.Region)
+	str("ACCESS_KEY_ID", &c.AccessKeyID)
+	str("SECRET_ACCESS_KEY", &c.SecretAccessKey)
+
+	str("JAVA_PATH", &c.JavaPath)
+	integer("MIN_JAVA_VERSION", &c.MinJavaVersion)
+	if v := os.Getenv("DDBLOCAL_JVM_OPTS"); v != "" {
+		c.JVMOptions = strings.Fields(v)
+	}
+	str("HEAP_SIZE", &c.HeapSize)
+	str("JAR", &c.JarPath)
+	str("LIB", &c.LibPath)
+	boolean("INSTALL", &c.Install)
+
+	str("DB_PATH", &c.DBPath)
+	boolean("IN_MEMORY", &c.InMemory)
+	boolean("NON_SHARED_DB", &c.NonSharedDB)
+	boolean("DELAY_TRANSIENT_STATUSES", &c.DelayTransientStatuses)
+	boolean("OPTIMIZE_DB_BEFORE_STARTUP", &c.OptimizeDBBeforeStartup)
+	if v := os.Getenv("DDBLOCAL_CORS"); v != "" {
+		c.CORS = strings.Split(v, ",")
+	}
+	boolean("DISABLE_TELEMETRY", &c.DisableTelemetry)
+
+	str("STARTUP_TIMEOUT", &c.StartupTimeout)
+	str("STARTUP_BACKOFF", &c.StartupBackoff)
+	str("STARTUP_MAX_BACKOFF", &c.StartupMaxBackoff)
+	str("TABLE_TIMEOUT", &c.TableTimeout)
+	str("GRACE_PERIOD", &c.GracePeriod)
+	integer("RESTARTS", &c.Restarts)
+
+	boolean("CONTAINER", &c.Container)
+	str("CONTAINER_CLI", &c.ContainerCLI)
+	str("CONTAINER_IMAGE", &c.ContainerImage)
+
+	str("TABLE_NAME_PREFIX", &c.TableNamePrefix)
+	boolean("KEEP_FAILED_TABLES", &c.KeepFailedTables)
+	boolean("SKIP_UNAVAILABLE", &c.SkipUnavailable)
+
+	boolean("SHARED", &c.Shared)
+	str("SHARE_DIR", &c.ShareDir)
+	str("PID_DIR", &c.PidDir)
+
+	if len(errs) > 0 {
+		return Config{}, errors.New(strings.Join(errs, "; "))
+	}
+	return c, nil
+}
@@ -0,0 +1,154 @@
+package ddblocal_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+)
+
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	ok(t, err)
+	ok(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ddblocal.yaml")
+	ok(t, ioutil.WriteFile(path, []byte(`
+port: 9000
+region: file
+heap_size: 1g
+startup_timeout: 5s
+reuse_policy: always-start-fresh
+`), 0644))
+	setenv(t, "DDBLOCAL_CONFIG", path)
+	setenv(t, "DDBLOCAL_PORT", "9001")
+	setenv(t, "DDBLOCAL_REGION", "env")
+	setenv(t, "DDBLOCAL_CORS", "a.example,b.example")
+
+	c, err := ddblocal.EffectiveConfig(ddblocal.CustomPort(9002))
+	ok(t, err)
+
+	equals(t, 9002, *c.Port)
+	equals(t, "env", c.Region)
+	equals(t, "1g", c.HeapSize)
+	equals(t, "5s", c.StartupTimeout)
+	equals(t, "always-start-fresh", c.ReusePolicy)
+	equals(t, []string{"a.example", "b.example"}, c.CORS)
+}
+
+func TestConfigFileDiscoveredInParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	ok(t, ioutil.WriteFile(filepath.Join(root, "ddblocal.json"), []byte(`{"endpoint": "http://dynamodb:8000", "in_memory": true}`), 0644))
+	dir := filepath.Join(root, "a", "b")
+	ok(t, os.MkdirAll(dir, 0755))
+	chdir(t, dir)
+
+	c, err := ddblocal.EffectiveConfig()
+	ok(t, err)
+
+	equals(t, "http://dynamodb:8000", c.Endpoint)
+	equals(t, true, *c.InMemory)
+}
+
+func TestConfigFileDiscoveryStopsAtModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	ok(t, ioutil.WriteFile(filepath.Join(root, "ddblocal.yaml"), []byte("region: outside\n"), 0644))
+	module := filepath.Join(root, "module")
+	dir := filepath.Join(module, "pkg")
+	ok(t, os.MkdirAll(dir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(module, "go.mod"), []byte("module example.com/module\n"), 0644))
+	chdir(t, dir)
+
+	c, err := ddblocal.EffectiveConfig()
+	ok(t, err)
+	equals(t, "test", c.Region)
+
+	path := filepath.Join(module, "ddblocal.yaml")
+	ok(t, ioutil.WriteFile(path, []byte("region: module\n"), 0644))
+	var w bytes.Buffer
+	c, err = ddblocal.EffectiveConfig(ddblocal.CustomLogWriter(&w))
+	ok(t, err)
+	equals(t, "module", c.Region)
+	equals(t, "ddblocal: using config file "+path+"\n", w.String())
+}
+
+func TestConfigEnvironmentDisablesFileBooleans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ddblocal.yaml")
+	ok(t, ioutil.WriteFile(path, []byte("shared: true\ncontainer: true\nin_memory: true\n"), 0644))
+	setenv(t, "DDBLOCAL_CONFIG", path)
+	setenv(t, "DDBLOCAL_SHARED", "false")
+	setenv(t, "DDBLOCAL_CONTAINER", "false")
+
+	c, err := ddblocal.EffectiveConfig()
+	ok(t, err)
+
+	equals(t, false, *c.Shared)
+	equals(t, "", c.ShareDir)
+	equals(t, false, *c.Container)
+	equals(t, true, *c.InMemory)
+}
+
+func TestConfigKeepsDefaultOfUnsetCredential(t *testing.T) {
+	setenv(t, "DDBLOCAL_ACCESS_KEY_ID", "key")
+
+	c, err := ddblocal.EffectiveConfig()
+	ok(t, err)
+
+	equals(t, "key", c.AccessKeyID)
+	equals(t, "test", c.SecretAccessKey)
+}
+
+func TestConfigShareDirDoesNotOverrideSharedFalse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ddblocal.yaml")
+	ok(t, ioutil.WriteFile(path, []byte("shared: false\nshare_dir: /tmp/ddblocal-share\n"), 0644))
+	setenv(t, "DDBLOCAL_CONFIG", path)
+
+	c, err := ddblocal.EffectiveConfig()
+	ok(t, err)
+
+	equals(t, false, *c.Shared)
+	equals(t, "", c.ShareDir)
+}
+
+func TestConfigRejectsInvalidValues(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		setenv(t, "DDBLOCAL_PORT", "eighty")
+		_, err := ddblocal.EffectiveConfig()
+		assert(t, err != nil, "expected an error")
+		equals(t, `invalid DDBLOCAL_PORT: "eighty"`, err.Error())
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ddblocal.yaml")
+		ok(t, ioutil.WriteFile(path, []byte("prot: 8000\n"), 0644))
+		setenv(t, "DDBLOCAL_CONFIG", path)
+		_, err := ddblocal.EffectiveConfig()
+		assert(t, err != nil, "expected an error")
+		assert(t, strings.HasPrefix(err.Error(), "invalid config file "+path), "unexpected error: %v", err)
+	})
+
+	t.Run("reuse policy", func(t *testing.T) {
+		setenv(t, "DDBLOCAL_REUSE_POLICY", "sometimes")
+		_, err := ddblocal.EffectiveConfig()
+		assert(t, err != nil, "expected an error")
+		equals(t, `invalid reuse policy: "sometimes"`, err.Error())
+	})
+}
+
+func TestConfigStringRedactsSecret(t *testing.T) {
+	c, err := ddblocal.EffectiveConfig(ddblocal.CustomCredentials("key", "secret"))
+	ok(t, err)
+
+	s := c.String()
+	assert(t, strings.Contains(s, "access_key_id: key\n"), "expected the access key in:\n%s", s)
+	assert(t, !strings.Contains(s, "secret\n"), "expected the secret to be redacted in:\n%s", s)
+}
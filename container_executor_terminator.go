@@ -0,0 +1,192 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultContainerCLI   = "docker"
+	defaultContainerImage = "amazon/dynamodb-local"
+
+	containerJarPath = "DynamoDBLocal.jar"
+	containerLibPath = "./DynamoDBLocal_lib"
+)
+
+// containerExecutorTerminator runs DynamoDB Local in a container using a
+// docker compatible container CLI. The process started by the executor is the
+// CLI running the container in the foreground, so its output and exit are
+// those of the container.
+type containerExecutorTerminator struct {
+	*executorTerminator
+	cli       string
+	image     string
+	container string
+}
+
+// Execute runs the DynamoDB Local image, translating the java command line
+// prepared by the Emulator so that it refers to the files in the image and
+// mapping the emulator port to the host.
+func (c *containerExecutorTerminator) Execute(name string, arg ...string) error {
+	return c.ExecuteContext(context.Background(), name, arg...)
+}
+
+// ExecuteContext works like Execute unless ctx is already done.
+func (c *containerExecutorTerminator) ExecuteContext(ctx context.Context, _ string, arg ...string) error {
+	name, err := genRandomString()
+	if err != nil {
+		return err
+	}
+
+	var port string
+	javaArgs := make([]string, len(arg))
+	for i, a := range arg {
+		switch {
+		case strings.HasPrefix(a, "-Djava.library.path="):
+			a = "-Djava.library.path=" + containerLibPath
+		case i > 0 && arg[i-1] == "-jar":
+			a = containerJarPath
+		case i > 0 && arg[i-1] == "-port":
+			port = a
+		}
+		javaArgs[i] = a
+	}
+	if port == "" {
+		return fmt.Errorf("missing -port argument")
+	}
+
+	container := fmt.Sprintf("ddblocal-%s-%s", port, name)
+	runArgs := append([]string{
+		"run",
+		// removes the container even if the CLI is stopped without Terminate
+		// being called, e.g. when the test process dies
+		"--rm",
+		"--name", container,
+		"-p", fmt.Sprintf("%s:%s", port, port),
+		c.image,
+	}, javaArgs...)
+	if err := c.executorTerminator.ExecuteContext(ctx, c.cli, runArgs...); err != nil {
+		return err
+	}
+	c.container = container
+	return nil
+}
+
+// Terminate stops the container, giving it the grace period to exit, makes
+// sure it's removed and reaps the CLI process. The container is removed and the CLI
+// process reaped even if stopping the container fails. An error is returned
+// if the container exited before being asked to with a non-zero status or if
+// it couldn't be stopped or removed.
+func (c *containerExecutorTerminator) Terminate() error {
+	return c.TerminateContext(context.Background())
+}
+
+// TerminateContext works like Terminate, but the container CLI commands are
+// bounded by ctx and the CLI process is killed right away if ctx is done
+// before it exits.
+func (c *containerExecutorTerminator) TerminateContext(ctx context.Context) error {
+	if c.container == "" {
+		return nil
+	}
+	defer func() {
+		c.container = ""
+		c.instance = nil
+	}()
+
+	var errs []error
+	select {
+	case <-c.done:
+		if c.err != nil {
+			errs = append(errs, fmt.Errorf("DynamoDB Local container exited unexpectedly: %w", c.err))
+		}
+	default:
+	}
+
+	seconds := strconv.Itoa(int(c.gracePeriod.Round(time.Second) / time.Second))
+	if err := c.run(ctx, "stop", "--time", seconds, c.container); err != nil {
+		errs = append(errs, err)
+	}
+	// usually already removed thanks to --rm
+	if err := c.run(ctx, "rm", "--force", c.container); err != nil && !strings.Contains(strings.ToLower(err.Error()), "no such container") {
+		errs = append(errs, err)
+	}
+
+	timer := time.NewTimer(c.gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		errs = append(errs, c.kill())
+	case <-timer.C:
+		errs = append(errs, c.kill())
+	}
+	return joinErrors(errs...)
+}
+
+// kill kills the CLI process and reaps it.
+func (c *containerExecutorTerminator) kill() error {
+	if err := killProcessGroup(c.instance.Process.Pid); err != nil {
+		return err
+	}
+	<-c.done
+	return nil
+}
+
+// joinErrors returns the only non-nil error, an error combining the messages
+// of several ones, or nil if there are none.
+func joinErrors(errs ...error) error {
+	var msgs []string
+	var last error
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+			last = err
+		}
+	}
+	switch len(msgs) {
+	case 0:
+		return nil
+	case 1:
+		return last
+	default:
+		return errors.New(strings.Join(msgs, "; "))
+	}
+}
+
+// run runs a container CLI command to completion.
+func (c *containerExecutorTerminator) run(ctx context.Context, arg ...string) error {
+	out, err := exec.CommandContext(ctx, c.cli, arg...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", c.cli, arg[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func newContainerExecutorTerminator(cli, image string, output io.Writer, gracePeriod time.Duration) *containerExecutorTerminator {
+	return &containerExecutorTerminator{
+		executorTerminator: newExecutorTerminator(output, gracePeriod),
+		cli:                cli,
+		image:              image,
+	}
+}
+
+// NewContainerExecutorTerminator returns a new instance of ExecutorTerminator
+// which runs DynamoDB Local in a container using the given docker compatible
+// container CLI (e.g. "docker" or "podman") and image. Default values are used
+// for empty arguments.
+func NewContainerExecutorTerminator(cli, image string) ExecutorTerminator {
+	if cli == "" {
+		cli = defaultContainerCLI
+	}
+	if image == "" {
+		image = defaultContainerImage
+	}
+	return newContainerExecutorTerminator(cli, image, nil, defaultGracePeriod)
+}
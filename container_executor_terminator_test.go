@@ -0,0 +1,97 @@
+package ddblocal_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+)
+
+func TestContainerBackendRunsAndRemovesContainer(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "docker.log")
+	fakeExecutable(t, "docker", fmt.Sprintf(`echo "$@" >> %[1]s
+case "$1" in
+run) echo $$ > %[1]s.pid; trap 'exit 143' TERM; echo ready; while true; do sleep 0.1; done;;
+stop) kill $(cat %[1]s.pid);;
+rm) echo "Error: No such container: $3" >&2; exit 1;;
+esac`, log))
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.ContainerBackend(),
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomLibPath("test_lib_path"),
+		ddblocal.CustomJarPath("test_jar_path"),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+
+	data, err := ioutil.ReadFile(log)
+	ok(t, err)
+	name := regexp.MustCompile(`ddblocal-8000-[0-9A-Za-z]+`).FindString(string(data))
+	assert(t, name != "", "expected a container name in %q", data)
+	equals(
+		t,
+		[]string{
+			"run --rm --name " + name + " -p 8000:8000 amazon/dynamodb-local -Djava.library.path=./DynamoDBLocal_lib -jar DynamoDBLocal.jar -port 8000 -sharedDb -inMemory",
+			"stop --time 10 " + name,
+			"rm --force " + name,
+		},
+		strings.Split(strings.TrimSpace(string(data)), "\n"),
+	)
+}
+
+func TestContainerBackendRemovesContainerWhenStopFails(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "docker.log")
+	fakeExecutable(t, "docker", fmt.Sprintf(`echo "$@" >> %[1]s
+case "$1" in
+run) echo $$ > %[1]s.pid; trap 'exit 137' TERM; echo ready; while true; do sleep 0.1; done;;
+stop) echo "daemon unreachable" >&2; exit 1;;
+rm) kill $(cat %[1]s.pid);;
+esac`, log))
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.ContainerBackend(),
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+	)
+	ok(t, err)
+	err = ddb.Close()
+	assert(t, err != nil, "expected an error")
+	equals(t, "docker stop failed: exit status 1: daemon unreachable", err.Error())
+
+	data, err := ioutil.ReadFile(log)
+	ok(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert(t, strings.HasPrefix(lines[len(lines)-1], "rm --force ddblocal-"), "expected the container to be removed, got %q", data)
+}
+
+func TestContainerBackendUsesCustomCLIAndImage(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "podman.log")
+	fakeExecutable(t, "podman", fmt.Sprintf(`echo "$@" >> %[1]s
+case "$1" in
+run) echo $$ > %[1]s.pid; echo ready; while true; do sleep 0.1; done;;
+stop) kill $(cat %[1]s.pid);;
+esac`, log))
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.ContainerBackend(),
+		ddblocal.CustomContainerCLI("podman"),
+		ddblocal.CustomContainerImage("example.com/dynamodb-local:test"),
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+
+	data, err := ioutil.ReadFile(log)
+	ok(t, err)
+	assert(t, strings.Contains(string(data), " example.com/dynamodb-local:test -Djava.library.path="), "expected the custom image to be run, got %q", data)
+}
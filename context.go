@@ -0,0 +1,118 @@
+package ddblocal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// executorTerminatorAdapter adapts an ExecutorTerminator to the
+// ExecutorTerminatorContext interface by ignoring the context.
+type executorTerminatorAdapter struct {
+	ExecutorTerminator
+}
+
+func (a executorTerminatorAdapter) ExecuteContext(_ context.Context, name string, arg ...string) error {
+	return a.Execute(name, arg...)
+}
+
+func (a executorTerminatorAdapter) TerminateContext(_ context.Context) error {
+	return a.Terminate()
+}
+
+func adaptExecutorTerminator(et ExecutorTerminator) ExecutorTerminatorContext {
+	if etc, ok := et.(ExecutorTerminatorContext); ok {
+		return etc
+	}
+	return executorTerminatorAdapter{et}
+}
+
+// presenceCheckerAdapter adapts a PresenceChecker to the
+// PresenceCheckerContext interface by ignoring the context.
+type presenceCheckerAdapter struct {
+	PresenceChecker
+}
+
+func (a presenceCheckerAdapter) IsPresentContext(_ context.Context, port int) bool {
+	return a.IsPresent(port)
+}
+
+func adaptPresenceChecker(pc PresenceChecker) PresenceCheckerContext {
+	if pcc, ok := pc.(PresenceCheckerContext); ok {
+		return pcc
+	}
+	return presenceCheckerAdapter{pc}
+}
+
+// proberAdapter adapts a PresenceCheckerContext to the Prober interface by
+// passing it the port of the endpoint. It can't tell a free port from one
+// occupied by a foreign service, so it reports every port without a DynamoDB
+// endpoint as free.
+type proberAdapter struct {
+	PresenceCheckerContext
+}
+
+func (a proberAdapter) Probe(ctx context.Context, endpoint string) ProbeResult {
+	port, err := endpointPort(endpoint)
+	if err == nil && a.IsPresentContext(ctx, port) {
+		return ProbeResult{State: PortDynamoDB}
+	}
+	return ProbeResult{State: PortFree}
+}
+
+func adaptProber(pc PresenceCheckerContext) Prober {
+	if p, ok := pc.(Prober); ok {
+		return p
+	}
+	if a, ok := pc.(presenceCheckerAdapter); ok {
+		if p, ok := a.PresenceChecker.(Prober); ok {
+			return p
+		}
+	}
+	return proberAdapter{pc}
+}
+
+// clientInitializerAdapter adapts a ClientInitializer to the
+// ClientInitializerContext interface by ignoring the context and passing it
+// the port of the endpoint.
+type clientInitializerAdapter struct {
+	ClientInitializer
+}
+
+func (a clientInitializerAdapter) InitClientContext(_ context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+	port, err := endpointPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return a.InitClient(port)
+}
+
+func adaptClientInitializer(ci ClientInitializer) ClientInitializerContext {
+	if cic, ok := ci.(ClientInitializerContext); ok {
+		return cic
+	}
+	return clientInitializerAdapter{ci}
+}
+
+// executor returns the ExecutorTerminator implementation used by the Emulator
+// so that the optional interfaces it implements can be discovered through the
+// adapter.
+func (e *Emulator) executor() interface{} {
+	if a, ok := e.et.(executorTerminatorAdapter); ok {
+		return a.ExecutorTerminator
+	}
+	return e.et
+}
+
+// testContext returns a context which is done when the deadline of the test,
+// if it has one, passes.
+func testContext(t testing.TB) (context.Context, context.CancelFunc) {
+	if dt, ok := t.(interface{ Deadline() (time.Time, bool) }); ok {
+		if deadline, ok := dt.Deadline(); ok {
+			return context.WithDeadline(context.Background(), deadline)
+		}
+	}
+	return context.WithCancel(context.Background())
+}
@@ -1,12 +1,16 @@
 package ddblocal
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
@@ -22,27 +26,131 @@ type ExecutorTerminator interface {
 	Terminate() error
 }
 
+// ExecutorTerminatorContext is a context-aware variant of ExecutorTerminator.
+// The context bounds the execution and termination of the process, not its
+// lifetime.
+type ExecutorTerminatorContext interface {
+	ExecuteContext(ctx context.Context, name string, arg ...string) error
+	TerminateContext(ctx context.Context) error
+}
+
+// ProcessMonitor is an optional interface which can be implemented by an
+// ExecutorTerminator to let the Emulator know when the DynamoDB Local process
+// has exited.
+type ProcessMonitor interface {
+	// Done returns a channel that is closed when the process exits.
+	Done() <-chan struct{}
+	// Err returns the reason the process exited, or nil if it exited
+	// cleanly. It is only meaningful after the Done channel is closed.
+	Err() error
+}
+
 // PresenceChecker checks if DynamoDB local process is present/running.
 type PresenceChecker interface {
 	IsPresent(port int) bool
 }
 
+// PresenceCheckerContext is a context-aware variant of PresenceChecker.
+type PresenceCheckerContext interface {
+	IsPresentContext(ctx context.Context, port int) bool
+}
+
+// Prober tells whether the port of an endpoint URL is free, occupied by a
+// DynamoDB compatible endpoint or occupied by a foreign service. It supersedes
+// the PresenceChecker and, as it is given the whole URL, also works with
+// endpoints not running on localhost.
+type Prober interface {
+	Probe(ctx context.Context, endpoint string) ProbeResult
+}
+
 // ClientInitializer initializes DynamoDB client for use with the emulator.
 type ClientInitializer interface {
 	InitClient(port int) (dynamodbiface.DynamoDBAPI, error)
 }
 
+// ClientInitializerContext is a context-aware variant of ClientInitializer. It
+// is given the whole endpoint URL rather than the port, so it also works with
+// endpoints not running on localhost.
+type ClientInitializerContext interface {
+	InitClientContext(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error)
+}
+
+// Installer installs the DynamoDB Local distribution.
+type Installer interface {
+	Install() (jarPath string, libPath string, err error)
+}
+
 // Emulator wraps the DynamoDB process for the purposes of programmatic control
 // in tests.
 type Emulator struct {
 	client  dynamodbiface.DynamoDBAPI
 	tng     StringGenerator
-	et      ExecutorTerminator
-	pc      PresenceChecker
-	ci      ClientInitializer
+	et      ExecutorTerminatorContext
+	pc      Prober
+	ci      ClientInitializerContext
+	inst    Installer
 	port    int
 	libPath string
 	jarPath string
+
+	startupTimeout    time.Duration
+	startupBackoff    time.Duration
+	startupMaxBackoff time.Duration
+	tableTimeout      time.Duration
+
+	tableNamePrefix  string
+	keepFailedTables bool
+
+	logs      *logBuffer
+	logWriter io.Writer
+
+	gracePeriod time.Duration
+
+	container      bool
+	containerCLI   string
+	containerImage string
+
+	shareDir string
+	shared   *sharedInstance
+	owned    bool
+	service  ProbeResult
+
+	portSet     bool
+	reusePolicy ReusePolicy
+	endpoint    string
+
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+
+	skipUnavailable bool
+	unavailable     error
+
+	javaPath       string
+	minJavaVersion int
+	checkRuntime   bool
+
+	jvmOptions       []string
+	customJVMOptions []string
+	heapSize         string
+
+	dbPath                  string
+	inMemory                bool
+	nonSharedDB             bool
+	delayTransientStatuses  bool
+	optimizeDBBeforeStartup bool
+	corsOrigins             []string
+	disableTelemetry        bool
+
+	pidDir      string
+	pidFile     string
+	trackLaunch bool
+
+	mu         sync.Mutex
+	closing    bool
+	restarts   int
+	exitErr    error
+	generation int
 }
 
 // Client returns an instance of DynamoDB client configured for the emulator.
@@ -50,79 +158,406 @@ func (e *Emulator) Client() dynamodbiface.DynamoDBAPI {
 	return e.client
 }
 
+// Port returns the port the DynamoDB Local instance used by the Emulator
+// listens on.
+func (e *Emulator) Port() int {
+	return e.port
+}
+
+// Endpoint returns the URL of the DynamoDB Local instance used by the Emulator.
+func (e *Emulator) Endpoint() string {
+	if e.endpoint != "" {
+		return e.endpoint
+	}
+	return localEndpoint(e.port)
+}
+
+// location describes where the Emulator expects DynamoDB Local in errors.
+func (e *Emulator) location() string {
+	if e.endpoint != "" {
+		return "at " + e.endpoint
+	}
+	return fmt.Sprintf("on port %d", e.port)
+}
+
+// ReusePolicy returns the policy the Emulator used to decide whether to reuse
+// an endpoint already running on its port.
+func (e *Emulator) ReusePolicy() ReusePolicy {
+	return e.reusePolicy
+}
+
+// Owned reports whether the DynamoDB Local process was started, and will be
+// terminated, by the Emulator. It is false when an endpoint which was already
+// running is reused.
+func (e *Emulator) Owned() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.owned
+}
+
+// Service describes the DynamoDB compatible endpoint used by the Emulator, as
+// detected by the Prober when it became ready or was found running.
+func (e *Emulator) Service() ProbeResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.service
+}
+
+// Logs returns the most recent output of the DynamoDB Local process started by
+// the Emulator. It is empty if the process was not started by the Emulator or
+// a custom ExecutorTerminator was used.
+func (e *Emulator) Logs() string {
+	return e.logs.String()
+}
+
+// Err returns an error wrapping ErrExited if the DynamoDB Local process
+// started by the Emulator exited unexpectedly and could not be restarted, and
+// nil otherwise.
+func (e *Emulator) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.exitErr
+}
+
 // Runner runs the test against a randomly named table, so that each test can
-// be run in parallel and in isolation from other tests. TableName in the
-// supplied tableDef will be overriden by a random name.
-func (e *Emulator) Runner(t testing.TB, tableDef *dynamodb.CreateTableInput, f func(client dynamodbiface.DynamoDBAPI, tableName string)) {
-	tableName, err := e.tng.Generate()
+// be run in parallel and in isolation from other tests. The table is created
+// from a copy of the supplied tableDef with TableName overriden by a random
+// name, so the same definition can be shared by parallel tests. The table is
+// deleted when the test completes, unless the test failed and the Emulator
+// keeps failed tables (see KeepFailedTables). Options such as Fixtures prepare
+// the table before the test is run. Requests made by the Runner are bounded by
+// the test deadline, if there is one.
+func (e *Emulator) Runner(t testing.TB, tableDef *dynamodb.CreateTableInput, f func(client dynamodbiface.DynamoDBAPI, tableName string), options ...RunnerOption) {
+	if !e.ready(t) {
+		return
+	}
+	var opts runnerOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	if len(opts.tableFixtures) > 0 {
+		t.Fatalf("TableFixtures can only be used with MultiTableRunner, use Fixtures instead")
+	}
+	items, err := readFixtures(opts.fixtures)
 	if err != nil {
-		t.Fatalf("failed to generate table name: %v", err)
+		t.Fatalf("failed to read fixtures: %v", err)
 	}
 
-	tableDef.TableName = aws.String(tableName)
-
-	if tableDef.ProvisionedThroughput == nil {
-		tableDef.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(1),
-			WriteCapacityUnits: aws.Int64(1),
+	tableName := e.createTable(t, tableDef)
+	if len(items) > 0 {
+		ctx, cancel := testContext(t)
+		defer cancel()
+		if err := e.loadFixtures(ctx, tableName, items); err != nil {
+			t.Fatalf("failed to load fixtures: %v", err)
 		}
 	}
+	f(e.client, tableName)
+}
 
-	for _, gsi := range tableDef.GlobalSecondaryIndexes {
-		if gsi.ProvisionedThroughput != nil {
-			continue
+// MultiTableRunner works like Runner, but creates a randomly named table for
+// each of the supplied table definitions, keyed by logical names, and passes
+// the map of logical to physical table names to the test. Fixtures are loaded
+// into the tables with TableFixtures.
+func (e *Emulator) MultiTableRunner(t testing.TB, tableDefs map[string]*dynamodb.CreateTableInput, f func(client dynamodbiface.DynamoDBAPI, tableNames map[string]string), options ...RunnerOption) {
+	if !e.ready(t) {
+		return
+	}
+	var opts runnerOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	if len(opts.fixtures) > 0 {
+		t.Fatalf("Fixtures can't be used with MultiTableRunner, use TableFixtures instead")
+	}
+	items := make(map[string][]map[string]*dynamodb.AttributeValue, len(opts.tableFixtures))
+	for name, paths := range opts.tableFixtures {
+		if _, ok := tableDefs[name]; !ok {
+			t.Fatalf("failed to read fixtures: no table named %q", name)
 		}
-		gsi.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(1),
-			WriteCapacityUnits: aws.Int64(1),
+		tableItems, err := readFixtures(paths)
+		if err != nil {
+			t.Fatalf("failed to read fixtures: %v", err)
 		}
+		items[name] = tableItems
 	}
 
-	_, err = e.client.CreateTable(tableDef)
-	if err != nil {
-		t.Fatalf("failed to create table: %v", err)
+	logicalNames := make([]string, 0, len(tableDefs))
+	for name := range tableDefs {
+		logicalNames = append(logicalNames, name)
 	}
+	sort.Strings(logicalNames)
 
-	t.Cleanup(func() {
-		if _, err := e.client.DeleteTable(&dynamodb.DeleteTableInput{
-			TableName: aws.String(tableName),
-		}); err != nil {
-			t.Fatalf("failed to delete table: %v", err)
+	tableNames := make(map[string]string, len(tableDefs))
+	for _, name := range logicalNames {
+		tableNames[name] = e.createTable(t, tableDefs[name])
+	}
+	if len(items) > 0 {
+		ctx, cancel := testContext(t)
+		defer cancel()
+		for _, name := range logicalNames {
+			if len(items[name]) == 0 {
+				continue
+			}
+			if err := e.loadFixtures(ctx, tableNames[name], items[name]); err != nil {
+				t.Fatalf("failed to load fixtures into %s: %v", name, err)
+			}
 		}
-	})
-
-	f(e.client, tableName)
+	}
+	f(e.client, tableNames)
 }
 
-// Close cleans up an instance of DynamoDB local server if it was started.
+// Close cleans up an instance of DynamoDB local server if it was started. An
+// error is returned if the process exited with an unexpected status or had to
+// be killed after not exiting within the grace period.
+//
+// An Emulator sharing its instance with other processes only terminates it
+// when it holds the last lease on the instance.
 func (e *Emulator) Close() error {
-	if err := e.et.Terminate(); err != nil {
+	return e.CloseContext(context.Background())
+}
+
+// CloseContext works like Close, but the process is killed right away if ctx
+// is done before it exits.
+func (e *Emulator) CloseContext(ctx context.Context) error {
+	e.mu.Lock()
+	e.closing = true
+	e.mu.Unlock()
+
+	if e.endpoint != "" || e.unavailable != nil {
+		return nil
+	}
+
+	if e.shared != nil {
+		return e.closeShared(ctx)
+	}
+	if err := e.et.TerminateContext(ctx); err != nil {
+		return err
+	}
+	e.removeLaunchRecord()
+	return nil
+}
+
+func (e *Emulator) closeShared(ctx context.Context) error {
+	unlock, err := e.shared.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	last, err := e.shared.release()
+	if err != nil || !last {
 		return err
 	}
+	if e.owned {
+		defer os.Remove(e.shared.pidFile())
+		return e.et.TerminateContext(ctx)
+	}
+	return e.shared.terminate(e.gracePeriod)
+}
+
+func (e *Emulator) start(ctx context.Context) error {
+	if e.shared == nil {
+		return e.launch(ctx)
+	}
+
+	// hold the lock until the instance is ready, so that other processes
+	// don't attempt to start it at the same time
+	unlock, err := e.shared.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// don't reuse an instance left behind by interrupted runs, as it still
+	// holds their tables
+	if err := e.shared.sweep(e.gracePeriod); err != nil {
+		return err
+	}
+	if err := e.shared.acquire(); err != nil {
+		return err
+	}
+	if err := e.launch(ctx); err != nil {
+		_, _ = e.shared.release()
+		return err
+	}
+	if pr, ok := e.executor().(pidReporter); ok && e.owned {
+		if err := e.shared.recordPid(pr.Pid(), e.args()); err != nil {
+			// nobody else could terminate the instance
+			_, _ = e.shared.release()
+			_ = e.et.TerminateContext(context.Background())
+			return err
+		}
+	}
 	return nil
 }
 
-func (e *Emulator) start() error {
-	if e.pc.IsPresent(e.port) {
+// launch runs an instance of DynamoDB Local unless the reuse policy says one
+// already present on the configured port should be used.
+func (e *Emulator) launch(ctx context.Context) error {
+	res := e.pc.Probe(ctx, e.Endpoint())
+	if res.State == PortForeign && e.reusePolicy != AlwaysStartFresh {
+		return fmt.Errorf("cannot start DynamoDB Local %s: %w", e.location(), ErrPortInUse)
+	}
+
+	switch e.reusePolicy {
+	case RequireExisting:
+		if res.State != PortDynamoDB {
+			return &unavailableError{fmt.Errorf("no DynamoDB compatible endpoint is running %s", e.location())}
+		}
+		e.service = res
 		return nil
+	case AlwaysStartFresh:
+		if res.State == PortFree {
+			break
+		}
+		if e.portSet {
+			return fmt.Errorf("cannot start DynamoDB Local on port %d: port is already in use", e.port)
+		}
+		port, err := freePort()
+		if err != nil {
+			return err
+		}
+		e.port = port
+	default:
+		if res.State == PortDynamoDB {
+			e.service = res
+			return nil
+		}
 	}
-	if err := e.et.Execute(
-		"java",
-		fmt.Sprintf("-Djava.library.path=%s", e.libPath),
-		"-jar",
-		e.jarPath,
-		"-port",
-		strconv.Itoa(e.port),
-		"-sharedDb",
-		"-inMemory",
-	); err != nil {
+	return e.run(ctx)
+}
+
+// run runs an instance of DynamoDB Local and waits until it is ready.
+func (e *Emulator) run(ctx context.Context) error {
+	if err := e.install(); err != nil {
 		return err
 	}
+	javaPath := "java"
+	if e.javaPath != "" {
+		javaPath = e.javaPath
+	}
+	if e.checkRuntime {
+		var err error
+		if javaPath, err = e.checkJava(ctx); err != nil {
+			return err
+		}
+	}
+	args := e.args()
+	if err := e.et.ExecuteContext(ctx, javaPath, args...); err != nil {
+		return err
+	}
+	if err := e.waitUntilReady(ctx); err != nil {
+		_ = e.et.TerminateContext(context.Background())
+		return err
+	}
+	e.owned = true
+	if pr, ok := e.executor().(pidReporter); ok && e.trackLaunch {
+		e.removeLaunchRecord()
+		if err := e.recordLaunch(pr.Pid(), args); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (e *Emulator) initClient() error {
-	client, err := e.ci.InitClient(e.port)
+// install uses the Installer, if one was configured, to provide the jar and
+// library paths which were not configured explicitly.
+func (e *Emulator) install() error {
+	if e.inst == nil || e.container || (e.jarPath != "" && e.libPath != "") {
+		return nil
+	}
+	jarPath, libPath, err := e.inst.Install()
+	if err != nil {
+		return fmt.Errorf("failed to install DynamoDB Local: %w", err)
+	}
+	if e.jarPath == "" {
+		e.jarPath = jarPath
+	}
+	if e.libPath == "" {
+		e.libPath = libPath
+	}
+	return nil
+}
+
+// waitUntilReady polls the Prober with an exponential backoff until
+// the DynamoDB Local process accepts requests, the startup timeout expires,
+// ctx is done or the process exits.
+func (e *Emulator) waitUntilReady(ctx context.Context) error {
+	var exited <-chan struct{}
+	pm, isMonitor := e.executor().(ProcessMonitor)
+	if isMonitor {
+		exited = pm.Done()
+	}
+
+	timeout := time.NewTimer(e.startupTimeout)
+	defer timeout.Stop()
+
+	backoff := e.startupBackoff
+	for {
+		if res := e.pc.Probe(ctx, e.Endpoint()); res.State == PortDynamoDB {
+			e.service = res
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("DynamoDB Local did not become ready on port %d: %w", e.port, ctx.Err())
+		case <-exited:
+			if err := pm.Err(); err != nil {
+				return fmt.Errorf("DynamoDB Local process exited before becoming ready: %w", err)
+			}
+			return fmt.Errorf("DynamoDB Local process exited before becoming ready")
+		case <-timeout.C:
+			return fmt.Errorf("DynamoDB Local did not become ready on port %d within %s", e.port, e.startupTimeout)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > e.startupMaxBackoff {
+			backoff = e.startupMaxBackoff
+		}
+	}
+}
+
+// waitForEndpoint polls the Prober with an exponential backoff until the
+// custom endpoint accepts requests, the startup timeout expires or ctx is
+// done, as the endpoint may still be starting, e.g. when it's a service
+// container started together with the tests.
+func (e *Emulator) waitForEndpoint(ctx context.Context) error {
+	timeout := time.NewTimer(e.startupTimeout)
+	defer timeout.Stop()
+
+	backoff := e.startupBackoff
+	for {
+		res := e.pc.Probe(ctx, e.endpoint)
+		if res.State == PortDynamoDB {
+			e.service = res
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no DynamoDB compatible endpoint is running %s: %w", e.location(), ctx.Err())
+		case <-timeout.C:
+			if res.State == PortForeign {
+				return fmt.Errorf("cannot use the endpoint %s: %w", e.location(), ErrPortInUse)
+			}
+			return &unavailableError{fmt.Errorf("no DynamoDB compatible endpoint is running %s", e.location())}
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > e.startupMaxBackoff {
+			backoff = e.startupMaxBackoff
+		}
+	}
+}
+
+// withLogs annotates err with the tail of the DynamoDB Local process output.
+func (e *Emulator) withLogs(err error) error {
+	tail := e.logs.Tail(logTailLines)
+	if tail == "" {
+		return err
+	}
+	return fmt.Errorf("%w\n\nDynamoDB Local output:\n%s", err, tail)
+}
+
+func (e *Emulator) initClient(ctx context.Context) error {
+	client, err := e.ci.InitClientContext(ctx, e.Endpoint())
 	if err != nil {
 		return err
 	}
@@ -141,9 +576,35 @@ func CustomStringGenerator(gen StringGenerator) EmulatorOption {
 	}
 }
 
+// TestTableNames makes the Runner derive table names from the name of the test,
+// so that leaked tables and failed requests can be traced back to it. The names
+// consist of the "ddblocal" prefix, the test name with characters which aren't
+// allowed in table names replaced, and a string from the StringGenerator to
+// keep them unique.
+func TestTableNames() EmulatorOption {
+	return CustomTableNamePrefix("ddblocal")
+}
+
+// CustomTableNamePrefix works like TestTableNames, but starts the table names
+// with the given prefix. The prefix may only contain a-z, A-Z, 0-9, '_', '-'
+// and '.', and an empty prefix restores the random table names.
+func CustomTableNamePrefix(prefix string) EmulatorOption {
+	return func(e *Emulator) {
+		e.tableNamePrefix = prefix
+	}
+}
+
 // CustomExecutorTerminator makes it possible to provide an alternative
 // implementaion of the ExecutorTerminator.
 func CustomExecutorTerminator(execTerm ExecutorTerminator) EmulatorOption {
+	return func(e *Emulator) {
+		e.et = adaptExecutorTerminator(execTerm)
+	}
+}
+
+// CustomExecutorTerminatorContext makes it possible to provide an alternative
+// context-aware implementation of the ExecutorTerminator.
+func CustomExecutorTerminatorContext(execTerm ExecutorTerminatorContext) EmulatorOption {
 	return func(e *Emulator) {
 		e.et = execTerm
 	}
@@ -153,23 +614,197 @@ func CustomExecutorTerminator(execTerm ExecutorTerminator) EmulatorOption {
 // implementation of the PresenceChecker to the emulator.
 func CustomPresenceChecker(presCheck PresenceChecker) EmulatorOption {
 	return func(e *Emulator) {
-		e.pc = presCheck
+		e.pc = adaptProber(adaptPresenceChecker(presCheck))
+	}
+}
+
+// CustomPresenceCheckerContext makes it possible to provide an alternative
+// context-aware implementation of the PresenceChecker to the emulator.
+func CustomPresenceCheckerContext(presCheck PresenceCheckerContext) EmulatorOption {
+	return func(e *Emulator) {
+		e.pc = adaptProber(presCheck)
+	}
+}
+
+// CustomProber makes it possible to provide an alternative implementation of
+// the Prober to the emulator.
+func CustomProber(prober Prober) EmulatorOption {
+	return func(e *Emulator) {
+		e.pc = prober
 	}
 }
 
 // CustomClientInitializer makes it possible to provide an alternative
 // implementaion of the ClientInitializer to the Emulator.
 func CustomClientInitializer(initClient ClientInitializer) EmulatorOption {
+	return func(e *Emulator) {
+		e.ci = adaptClientInitializer(initClient)
+	}
+}
+
+// CustomClientInitializerContext makes it possible to provide an alternative
+// context-aware implementation of the ClientInitializer to the Emulator.
+func CustomClientInitializerContext(initClient ClientInitializerContext) EmulatorOption {
 	return func(e *Emulator) {
 		e.ci = initClient
 	}
 }
 
+// CustomInstaller makes it possible to have DynamoDB Local installed
+// automatically when the jar or library paths are not configured, e.g. by
+// passing NewInstaller().
+func CustomInstaller(inst Installer) EmulatorOption {
+	return func(e *Emulator) {
+		e.inst = inst
+	}
+}
+
 // CustomPort makes it possible to override the default port configuration of
 // the emulator.
 func CustomPort(port int) EmulatorOption {
 	return func(e *Emulator) {
 		e.port = port
+		// a random port may be replaced by another one
+		e.portSet = port != 0
+	}
+}
+
+// CustomEndpoint makes the emulator use the DynamoDB compatible endpoint at
+// the given URL (e.g. "http://dynamodb:8000" or "https://example.com") rather
+// than one on localhost. The Emulator doesn't start, monitor or terminate any
+// process when this option is used, but waits for the endpoint to accept
+// requests for up to the startup timeout. The port-based PresenceChecker and
+// ClientInitializer can't be used with it, as they would be given the port of
+// the endpoint without its host; use CustomProber and
+// CustomClientInitializerContext instead.
+func CustomEndpoint(url string) EmulatorOption {
+	return func(e *Emulator) {
+		e.endpoint = url
+	}
+}
+
+// CustomRegion makes it possible to override the region used by the client
+// initialized by the default ClientInitializer ("test" by default).
+func CustomRegion(region string) EmulatorOption {
+	return func(e *Emulator) {
+		e.region = region
+	}
+}
+
+// CustomCredentials makes it possible to override the static credentials used
+// by the client initialized by the default ClientInitializer ("test" and
+// "test" by default).
+func CustomCredentials(accessKeyID, secretAccessKey string) EmulatorOption {
+	return func(e *Emulator) {
+		e.accessKeyID = accessKeyID
+		e.secretAccessKey = secretAccessKey
+	}
+}
+
+// CustomReusePolicy makes it possible to choose what the emulator does when a
+// DynamoDB compatible endpoint is already running on its port. The default
+// policy is ReuseIfPresent.
+func CustomReusePolicy(policy ReusePolicy) EmulatorOption {
+	return func(e *Emulator) {
+		e.reusePolicy = policy
+	}
+}
+
+// RandomPort makes the emulator listen on a currently unused port chosen by
+// the operating system instead of the default one. The chosen port is
+// reported by the Port method of the Emulator.
+func RandomPort() EmulatorOption {
+	return CustomPort(0)
+}
+
+// CustomJavaPath makes it possible to override the Java runtime, which is
+// otherwise looked up in JAVA_HOME and then in PATH.
+func CustomJavaPath(javaPath string) EmulatorOption {
+	return func(e *Emulator) {
+		e.javaPath = javaPath
+	}
+}
+
+// CustomMinJavaVersion makes it possible to override the minimum major
+// version of the Java runtime required to launch DynamoDB Local (11 by
+// default), e.g. when using an older release.
+func CustomMinJavaVersion(version int) EmulatorOption {
+	return func(e *Emulator) {
+		e.minJavaVersion = version
+	}
+}
+
+// CustomJVMOptions makes it possible to pass additional options (e.g. -X or -D
+// flags) to the Java runtime launching DynamoDB Local. They are passed after
+// the ones in the DDBLOCAL_JVM_OPTS environment variable, so they take
+// precedence.
+func CustomJVMOptions(options ...string) EmulatorOption {
+	return func(e *Emulator) {
+		e.customJVMOptions = append(e.customJVMOptions, options...)
+	}
+}
+
+// CustomHeapSize makes it possible to set the maximum heap size of the Java
+// runtime launching DynamoDB Local, e.g. "512m" or "2g".
+func CustomHeapSize(size string) EmulatorOption {
+	return func(e *Emulator) {
+		e.heapSize = size
+	}
+}
+
+// CustomDBPath makes DynamoDB Local store its database in the given directory
+// instead of keeping it in memory.
+func CustomDBPath(dbPath string) EmulatorOption {
+	return func(e *Emulator) {
+		e.dbPath = dbPath
+	}
+}
+
+// InMemory makes it explicit that DynamoDB Local should keep its database in
+// memory, which is the default. It cannot be combined with CustomDBPath.
+func InMemory() EmulatorOption {
+	return func(e *Emulator) {
+		e.inMemory = true
+	}
+}
+
+// NonSharedDB makes DynamoDB Local use a separate database for each
+// combination of credentials and region instead of a single shared one.
+func NonSharedDB() EmulatorOption {
+	return func(e *Emulator) {
+		e.nonSharedDB = true
+	}
+}
+
+// DelayTransientStatuses makes DynamoDB Local introduce delays for certain
+// operations (e.g. tables being CREATING before becoming ACTIVE), simulating
+// the behaviour of the real service more closely.
+func DelayTransientStatuses() EmulatorOption {
+	return func(e *Emulator) {
+		e.delayTransientStatuses = true
+	}
+}
+
+// OptimizeDBBeforeStartup makes DynamoDB Local optimize the underlying
+// database tables before starting up. It requires CustomDBPath.
+func OptimizeDBBeforeStartup() EmulatorOption {
+	return func(e *Emulator) {
+		e.optimizeDBBeforeStartup = true
+	}
+}
+
+// CustomCORS makes DynamoDB Local allow cross-origin requests from the given
+// origins.
+func CustomCORS(origins ...string) EmulatorOption {
+	return func(e *Emulator) {
+		e.corsOrigins = origins
+	}
+}
+
+// DisableTelemetry opts DynamoDB Local out of telemetry collection.
+func DisableTelemetry() EmulatorOption {
+	return func(e *Emulator) {
+		e.disableTelemetry = true
 	}
 }
 
@@ -189,30 +824,207 @@ func CustomJarPath(jarPath string) EmulatorOption {
 	}
 }
 
+// CustomStartupTimeout makes it possible to override the default time New
+// waits for a freshly started DynamoDB Local process to accept requests.
+func CustomStartupTimeout(timeout time.Duration) EmulatorOption {
+	return func(e *Emulator) {
+		e.startupTimeout = timeout
+	}
+}
+
+// CustomStartupBackoff makes it possible to override the default interval
+// between readiness checks of a freshly started DynamoDB Local process. The
+// interval starts at initial and doubles after every check up to max.
+func CustomStartupBackoff(initial, max time.Duration) EmulatorOption {
+	return func(e *Emulator) {
+		e.startupBackoff = initial
+		e.startupMaxBackoff = max
+	}
+}
+
+// CustomTableTimeout makes it possible to override the default time the
+// Runner waits for a table and its global secondary indexes to become ACTIVE
+// after creating it, and for the table to disappear after deleting it.
+func CustomTableTimeout(timeout time.Duration) EmulatorOption {
+	return func(e *Emulator) {
+		e.tableTimeout = timeout
+	}
+}
+
+// CustomLogWriter makes it possible to receive the output of the DynamoDB
+// Local process started by the Emulator in addition to it being retained for
+// the Logs method.
+func CustomLogWriter(w io.Writer) EmulatorOption {
+	return func(e *Emulator) {
+		e.logWriter = w
+	}
+}
+
+// CustomGracePeriod makes it possible to override the default time the
+// DynamoDB Local process started by the Emulator is given to exit after being
+// asked to on Close, before it is forcefully killed.
+func CustomGracePeriod(gracePeriod time.Duration) EmulatorOption {
+	return func(e *Emulator) {
+		e.gracePeriod = gracePeriod
+	}
+}
+
+// ContainerBackend makes the Emulator run DynamoDB Local in a container
+// instead of launching java directly, so that neither a Java runtime nor the
+// DynamoDB Local distribution need to be installed.
+func ContainerBackend() EmulatorOption {
+	return func(e *Emulator) {
+		e.container = true
+	}
+}
+
+// CustomContainerCLI makes it possible to override the default container CLI
+// ("docker") used by the container backend, e.g. with "podman".
+func CustomContainerCLI(cli string) EmulatorOption {
+	return func(e *Emulator) {
+		e.containerCLI = cli
+	}
+}
+
+// CustomContainerImage makes it possible to override the default image
+// ("amazon/dynamodb-local") used by the container backend.
+func CustomContainerImage(image string) EmulatorOption {
+	return func(e *Emulator) {
+		e.containerImage = image
+	}
+}
+
+// Shared makes the Emulator share its DynamoDB Local instance with Emulators
+// in other processes using the same port, such as the test binaries of
+// different packages run by go test ./... The instance is started by the
+// first process and terminated when the last one closes its Emulator. The
+// coordination state is kept in the ddblocal directory in the system temporary
+// directory.
+func Shared() EmulatorOption {
+	return CustomShareDir(filepath.Join(os.TempDir(), "ddblocal"))
+}
+
+// CustomShareDir works like Shared, but keeps the coordination state in the
+// given directory.
+func CustomShareDir(dir string) EmulatorOption {
+	return func(e *Emulator) {
+		e.shareDir = dir
+	}
+}
+
+// CustomPidDir makes it possible to override the default directory (ddblocal/pids
+// in the system temporary directory) in which the Emulator records the
+// DynamoDB Local processes it launches. New terminates recorded processes
+// whose launching process died without closing its Emulator, e.g. when a test
+// run was interrupted.
+func CustomPidDir(dir string) EmulatorOption {
+	return func(e *Emulator) {
+		e.pidDir = dir
+	}
+}
+
+// CustomRestarts makes it possible to have the DynamoDB Local process started
+// by the Emulator restarted automatically, at most the given number of times,
+// when it exits unexpectedly. Tables created before the restart are lost
+// unless the database is stored on disk.
+func CustomRestarts(restarts int) EmulatorOption {
+	return func(e *Emulator) {
+		e.restarts = restarts
+	}
+}
+
+const (
+	logBufferSize      = 64 * 1024
+	logTailLines       = 20
+	defaultGracePeriod = 10 * time.Second
+)
+
+// New starts an instance of DynamoDB Local (unless one is already running on
+// the configured port), waits until it accepts requests and returns an
+// Emulator configured to use it.
+//
+// The default configuration is overridden by the config file, the DDBLOCAL_
+// environment variables (see Config) and the options, in this order.
 func New(options ...EmulatorOption) (*Emulator, error) {
-	// default Emulator configuration
-	ddb := &Emulator{
-		pc:      NewPresenceChecker(),
-		et:      NewExecutorTerminator(),
-		tng:     NewStringGenerator(),
-		ci:      NewClientInitialier(),
-		port:    8000,
-		libPath: os.Getenv("DDBLOCAL_LIB"),
-		jarPath: os.Getenv("DDBLOCAL_JAR"),
-	}
-
-	// apply option overrides
-	for _, option := range options {
-		option(ddb)
+	return NewWithContext(context.Background(), options...)
+}
+
+// NewWithContext works like New, but gives up on starting DynamoDB Local when
+// ctx is done.
+func NewWithContext(ctx context.Context, options ...EmulatorOption) (*Emulator, error) {
+	ddb, err := configure(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// use the endpoint as it is without managing any process
+	if ddb.endpoint != "" {
+		port, err := endpointPort(ddb.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		ddb.port = port
+		ddb.reusePolicy = RequireExisting
+		if err := ddb.waitForEndpoint(ctx); err != nil {
+			return ddb.failed(err)
+		}
+		if err := ddb.initClient(ctx); err != nil {
+			return nil, err
+		}
+		return ddb, nil
+	}
+
+	// pick a free port if one was not specified
+	if ddb.port == 0 {
+		port, err := freePort()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find a free port: %w", err)
+		}
+		ddb.port = port
+	}
+
+	// coordinate the use of the instance with other processes
+	if ddb.shareDir != "" {
+		shared, err := newSharedInstance(ddb.shareDir, ddb.port)
+		if err != nil {
+			return nil, err
+		}
+		ddb.shared = shared
+	}
+
+	// capture the output of the process unless a custom ExecutorTerminator
+	// was provided
+	if ddb.et == nil {
+		var output io.Writer = ddb.logs
+		if ddb.logWriter != nil {
+			output = io.MultiWriter(ddb.logs, ddb.logWriter)
+		}
+		if ddb.container {
+			ddb.et = newContainerExecutorTerminator(ddb.containerCLI, ddb.containerImage, output, ddb.gracePeriod)
+		} else {
+			// a shared instance has to outlive the process launching it
+			et := newExecutorTerminator(output, ddb.gracePeriod)
+			et.detached = ddb.shared != nil
+			ddb.et = et
+			ddb.checkRuntime = true
+			// a shared instance is recorded in the share directory instead
+			ddb.trackLaunch = ddb.shared == nil
+			// clean up instances left behind by interrupted runs
+			sweepOrphans(ddb.pidDir, ddb.gracePeriod)
+		}
 	}
 
 	// run an instance of the DynamoDB local server if not running already
-	if err := ddb.start(); err != nil {
-		return nil, err
+	if err := ddb.start(ctx); err != nil {
+		return ddb.failed(ddb.withLogs(err))
 	}
 
+	// detect unexpected exits of the process started by the Emulator
+	ddb.monitor()
+
 	// init DynamoDB client
-	if err := ddb.initClient(); err != nil {
+	if err := ddb.initClient(ctx); err != nil {
+		_ = ddb.CloseContext(context.Background())
 		return nil, err
 	}
 
@@ -1,16 +1,28 @@
 package ddblocal_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/fwojciec/ddblocal"
@@ -23,6 +35,9 @@ var (
 )
 
 func TestMain(m *testing.M) {
+	if mode := os.Getenv("DDBLOCAL_TEST_MAIN"); mode != "" {
+		mainHelper(m, mode)
+	}
 	flag.Parse()
 	exitCode, err := runTests(m)
 	if err != nil {
@@ -67,9 +82,7 @@ func TestInitDefaults(t *testing.T) {
 
 	// need custom presence checker because during integration test the
 	// server is present which prevents the executorMock from being called
-	pcm := &mocks.PresenceCheckerMock{
-		IsPresentFunc: func(port int) bool { return false },
-	}
+	pcm := startingPresenceChecker()
 
 	_, err := ddblocal.New(
 		ddblocal.CustomExecutorTerminator(etm),
@@ -107,8 +120,9 @@ func TestInitCustomPort(t *testing.T) {
 	var recPortPC int
 	pcm := &mocks.PresenceCheckerMock{
 		IsPresentFunc: func(port int) bool {
+			started := recPortPC != 0
 			recPortPC = port
-			return false
+			return started
 		},
 	}
 
@@ -133,7 +147,7 @@ func TestInitCustomPort(t *testing.T) {
 	assert(t, contains([]string{"-port", "8888"}, recArgs), "should pass the correct port value in args")
 }
 
-func TestInitCustomLibPath(t *testing.T) {
+func TestInitRandomPort(t *testing.T) {
 	t.Parallel()
 
 	var recArgs []string
@@ -144,10 +158,71 @@ func TestInitCustomLibPath(t *testing.T) {
 		},
 	}
 
-	pcm := &mocks.PresenceCheckerMock{
-		IsPresentFunc: func(port int) bool { return false },
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return nil, nil
+		},
+	}
+
+	pcm := startingPresenceChecker()
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomClientInitializer(cim),
+		ddblocal.RandomPort(),
+	)
+	ok(t, err)
+
+	port := ddb.Port()
+	assert(t, port != 0 && port != 8000, "expected a random port, got %d", port)
+	equals(t, fmt.Sprintf("http://localhost:%d", port), ddb.Endpoint())
+	equals(t, port, pcm.IsPresentCalls()[0].Port)
+	equals(t, port, cim.InitClientCalls()[0].Port)
+	assert(t, contains([]string{"-port", strconv.Itoa(port)}, recArgs), "should pass the chosen port value in args")
+}
+
+func TestInitClosesEmulatorWhenClientFails(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(_ string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return nil, fmt.Errorf("test error")
+		},
+	}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomClientInitializer(cim),
+		ddblocal.CustomShareDir(dir),
+	)
+	assert(t, err != nil, "expected an error")
+	equals(t, "test error", err.Error())
+	equals(t, 1, len(etm.TerminateCalls()))
+
+	leases, err := filepath.Glob(filepath.Join(dir, "8000", "*.lease"))
+	ok(t, err)
+	equals(t, 0, len(leases))
+}
+
+func TestInitCustomLibPath(t *testing.T) {
+	t.Parallel()
+
+	var recArgs []string
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(_ string, arg ...string) error {
+			recArgs = arg
+			return nil
+		},
 	}
 
+	pcm := startingPresenceChecker()
+
 	_, err := ddblocal.New(
 		ddblocal.CustomExecutorTerminator(etm),
 		ddblocal.CustomPresenceChecker(pcm),
@@ -169,9 +244,7 @@ func TestInitCustomJarPath(t *testing.T) {
 		},
 	}
 
-	pcm := &mocks.PresenceCheckerMock{
-		IsPresentFunc: func(port int) bool { return false },
-	}
+	pcm := startingPresenceChecker()
 
 	_, err := ddblocal.New(
 		ddblocal.CustomExecutorTerminator(etm),
@@ -183,103 +256,252 @@ func TestInitCustomJarPath(t *testing.T) {
 	assert(t, contains([]string{"-jar", "test_jar_path"}, recArgs), "should pass the correct lib path value in args")
 }
 
-func TestClose(t *testing.T) {
+func TestInitInstallsWhenPathsUnset(t *testing.T) {
 	t.Parallel()
 
+	var recArgs []string
 	etm := &mocks.ExecutorTerminatorMock{
-		ExecuteFunc:   func(name string, arg ...string) error { return nil },
-		TerminateFunc: func() error { return nil },
+		ExecuteFunc: func(_ string, arg ...string) error {
+			recArgs = arg
+			return nil
+		},
 	}
 
-	pcm := &mocks.PresenceCheckerMock{
-		IsPresentFunc: func(port int) bool { return false },
+	im := &mocks.InstallerMock{
+		InstallFunc: func() (string, string, error) {
+			return "installed_jar_path", "installed_lib_path", nil
+		},
 	}
 
-	ddb, err := ddblocal.New(
+	_, err := ddblocal.New(
 		ddblocal.CustomExecutorTerminator(etm),
-		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomInstaller(im),
+		ddblocal.CustomLibPath("test_lib_path"),
+		ddblocal.CustomJarPath(""),
 	)
 	ok(t, err)
 
-	err = ddb.Close()
-	ok(t, err)
-
-	equals(t, 1, len(etm.TerminateCalls()))
+	equals(t, 1, len(im.InstallCalls()))
+	assert(t, contains([]string{"-Djava.library.path=test_lib_path"}, recArgs), "should not override the configured lib path")
+	assert(t, contains([]string{"-jar", "installed_jar_path"}, recArgs), "should pass the installed jar path in args")
 }
 
-func TestRunnerCreatesTableCorrectly(t *testing.T) {
+func TestInitSkipsInstallWhenPathsSet(t *testing.T) {
 	t.Parallel()
 
 	etm := &mocks.ExecutorTerminatorMock{
-		ExecuteFunc:   func(name string, arg ...string) error { return nil },
-		TerminateFunc: func() error { return nil },
+		ExecuteFunc: func(_ string, arg ...string) error { return nil },
 	}
 
-	pcm := &mocks.PresenceCheckerMock{
-		IsPresentFunc: func(port int) bool { return false },
+	im := &mocks.InstallerMock{}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomInstaller(im),
+		ddblocal.CustomLibPath("test_lib_path"),
+		ddblocal.CustomJarPath("test_jar_path"),
+	)
+	ok(t, err)
+
+	equals(t, 0, len(im.InstallCalls()))
+}
+
+func TestInitJVMOptions(t *testing.T) {
+	setenv(t, "DDBLOCAL_JVM_OPTS", "-Xss1m  -Dfoo=bar")
+
+	var recArgs []string
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(_ string, arg ...string) error {
+			recArgs = arg
+			return nil
+		},
 	}
 
-	sgm := &mocks.StringGeneratorMock{
-		GenerateFunc: func() (string, error) {
-			return "test_name", nil
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomLibPath("test_lib_path"),
+		ddblocal.CustomHeapSize("512m"),
+		ddblocal.CustomJVMOptions("-Dbaz=qux"),
+	)
+	ok(t, err)
+
+	equals(
+		t,
+		[]string{"-Xss1m", "-Dfoo=bar", "-Xmx512m", "-Dbaz=qux", "-Djava.library.path=test_lib_path"},
+		recArgs[:5],
+	)
+}
+
+func TestInitFlags(t *testing.T) {
+	t.Parallel()
+
+	var recArgs []string
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(_ string, arg ...string) error {
+			recArgs = arg
+			return nil
 		},
 	}
 
-	var recCreateTableInput *dynamodb.CreateTableInput
-	ddbcm := &mocks.DynamoDBAPIMock{
-		CreateTableFunc: func(in1 *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
-			recCreateTableInput = in1
-			return nil, nil
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomDBPath("test_db_path"),
+		ddblocal.NonSharedDB(),
+		ddblocal.DelayTransientStatuses(),
+		ddblocal.OptimizeDBBeforeStartup(),
+		ddblocal.CustomCORS("http://localhost:3000", "http://localhost:8080"),
+		ddblocal.DisableTelemetry(),
+	)
+	ok(t, err)
+
+	assert(
+		t,
+		contains(
+			[]string{
+				"-port", "8000",
+				"-dbPath", "test_db_path",
+				"-delayTransientStatuses",
+				"-optimizeDbBeforeStartup",
+				"-cors", "http://localhost:3000,http://localhost:8080",
+				"-disableTelemetry",
+			},
+			recArgs,
+		),
+		"should pass the flags in args, got %v", recArgs,
+	)
+	assert(t, !contains([]string{"-sharedDb"}, recArgs), "should not pass -sharedDb in args")
+	assert(t, !contains([]string{"-inMemory"}, recArgs), "should not pass -inMemory in args")
+}
+
+func TestInitRejectsIncompatibleOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []ddblocal.EmulatorOption
+		err     string
+	}{
+		{
+			name:    "in memory with db path",
+			options: []ddblocal.EmulatorOption{ddblocal.InMemory(), ddblocal.CustomDBPath("test_db_path")},
+			err:     "incompatible options: -inMemory and -dbPath",
 		},
-		DeleteTableFunc: func(in1 *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
-			return nil, nil
+		{
+			name:    "optimize without db path",
+			options: []ddblocal.EmulatorOption{ddblocal.OptimizeDBBeforeStartup()},
+			err:     "incompatible options: -optimizeDbBeforeStartup requires -dbPath",
 		},
-	}
-	cim := &mocks.ClientInitializerMock{
-		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
-			return ddbcm, nil
+		{
+			name:    "container with db path",
+			options: []ddblocal.EmulatorOption{ddblocal.ContainerBackend(), ddblocal.CustomDBPath("test_db_path")},
+			err:     "incompatible options: -dbPath is not supported by the container backend",
+		},
+		{
+			name:    "invalid cors origin",
+			options: []ddblocal.EmulatorOption{ddblocal.CustomCORS("a,b")},
+			err:     `invalid -cors origin: "a,b"`,
+		},
+		{
+			name:    "shared requiring existing instance",
+			options: []ddblocal.EmulatorOption{ddblocal.Shared(), ddblocal.CustomReusePolicy(ddblocal.RequireExisting)},
+			err:     "incompatible options: Shared and the require-existing reuse policy",
+		},
+		{
+			name:    "shared with random port",
+			options: []ddblocal.EmulatorOption{ddblocal.Shared(), ddblocal.RandomPort()},
+			err:     "incompatible options: Shared and RandomPort",
+		},
+		{
+			name:    "shared with container",
+			options: []ddblocal.EmulatorOption{ddblocal.Shared(), ddblocal.ContainerBackend()},
+			err:     "incompatible options: Shared and ContainerBackend",
+		},
+		{
+			name:    "endpoint with container",
+			options: []ddblocal.EmulatorOption{ddblocal.CustomEndpoint("http://dynamodb:8000"), ddblocal.ContainerBackend()},
+			err:     "incompatible options: CustomEndpoint and ContainerBackend",
+		},
+		{
+			name:    "endpoint with port-based presence checker",
+			options: []ddblocal.EmulatorOption{ddblocal.CustomEndpoint("http://dynamodb:8000"), ddblocal.CustomPresenceChecker(&mocks.PresenceCheckerMock{})},
+			err:     "incompatible options: CustomEndpoint and CustomPresenceChecker, use CustomProber instead",
+		},
+		{
+			name:    "endpoint with port-based client initializer",
+			options: []ddblocal.EmulatorOption{ddblocal.CustomEndpoint("http://dynamodb:8000"), ddblocal.CustomClientInitializer(&mocks.ClientInitializerMock{})},
+			err:     "incompatible options: CustomEndpoint and CustomClientInitializer, use CustomClientInitializerContext instead",
 		},
+		{
+			name:    "invalid table name prefix",
+			options: []ddblocal.EmulatorOption{ddblocal.CustomTableNamePrefix("my app")},
+			err:     `invalid table name prefix: "my app"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			etm := &mocks.ExecutorTerminatorMock{}
+			_, err := ddblocal.New(append(tt.options, ddblocal.CustomExecutorTerminator(etm))...)
+			assert(t, err != nil, "expected an error")
+			equals(t, tt.err, err.Error())
+			equals(t, 0, len(etm.ExecuteCalls()))
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
 	}
 
+	pcm := startingPresenceChecker()
+
 	ddb, err := ddblocal.New(
 		ddblocal.CustomExecutorTerminator(etm),
 		ddblocal.CustomPresenceChecker(pcm),
-		ddblocal.CustomStringGenerator(sgm),
-		ddblocal.CustomClientInitializer(cim),
 	)
 	ok(t, err)
 
-	var recTableName string
-	tableInput := &dynamodb.CreateTableInput{
-		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
-			{IndexName: aws.String("GSI")},
-		},
+	err = ddb.Close()
+	ok(t, err)
+
+	equals(t, 1, len(etm.TerminateCalls()))
+}
+
+func TestInitWaitsUntilReady(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
 	}
-	ddb.Runner(t, tableInput, func(_ dynamodbiface.DynamoDBAPI, tableName string) {
-		recTableName = tableName
-	})
-	equals(t, "test_name", recTableName)
 
-	expCreateTableInput := &dynamodb.CreateTableInput{
-		TableName: aws.String("test_name"),
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(1),
-			WriteCapacityUnits: aws.Int64(1),
-		},
-		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
-			{
-				IndexName: aws.String("GSI"),
-				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-					ReadCapacityUnits:  aws.Int64(1),
-					WriteCapacityUnits: aws.Int64(1),
-				},
-			},
+	var calls int32
+	pcm := &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool {
+			return atomic.AddInt32(&calls, 1) > 3
 		},
 	}
-	equals(t, expCreateTableInput, recCreateTableInput)
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomStartupBackoff(time.Millisecond, time.Millisecond),
+	)
+	ok(t, err)
+
+	equals(t, 4, len(pcm.IsPresentCalls()))
 }
 
-func TestRunnerDeletesTableCorrectly(t *testing.T) {
+func TestInitFailsIfNeverReady(t *testing.T) {
 	t.Parallel()
 
 	etm := &mocks.ExecutorTerminatorMock{
@@ -291,55 +513,853 @@ func TestRunnerDeletesTableCorrectly(t *testing.T) {
 		IsPresentFunc: func(port int) bool { return false },
 	}
 
-	sgm := &mocks.StringGeneratorMock{
-		GenerateFunc: func() (string, error) {
-			return "test_name", nil
-		},
-	}
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomStartupTimeout(20*time.Millisecond),
+		ddblocal.CustomStartupBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+	assert(t, err != nil, "expected an error")
+	assert(t, strings.Contains(err.Error(), "did not become ready"), "unexpected error: %v", err)
+	equals(t, 1, len(etm.TerminateCalls()))
+}
 
-	var recDeleteTableInput *dynamodb.DeleteTableInput
-	ddbcm := &mocks.DynamoDBAPIMock{
-		CreateTableFunc: func(in1 *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
-			return nil, nil
+func TestInitFailsIfProcessExitsEarly(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	close(done)
+	et := &monitoredExecutorTerminator{
+		ExecutorTerminatorMock: &mocks.ExecutorTerminatorMock{
+			ExecuteFunc:   func(name string, arg ...string) error { return nil },
+			TerminateFunc: func() error { return nil },
 		},
-		DeleteTableFunc: func(in1 *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
-			recDeleteTableInput = in1
-			return nil, nil
+		ProcessMonitorMock: &mocks.ProcessMonitorMock{
+			DoneFunc: func() <-chan struct{} { return done },
+			ErrFunc:  func() error { return fmt.Errorf("exit status 1") },
 		},
 	}
-	cim := &mocks.ClientInitializerMock{
-		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
-			return ddbcm, nil
-		},
+
+	pcm := &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool { return false },
 	}
 
-	ddb, err := ddblocal.New(
-		ddblocal.CustomExecutorTerminator(etm),
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(et),
 		ddblocal.CustomPresenceChecker(pcm),
-		ddblocal.CustomStringGenerator(sgm),
-		ddblocal.CustomClientInitializer(cim),
 	)
-	ok(t, err)
+	assert(t, err != nil, "expected an error")
+	equals(t, "DynamoDB Local process exited before becoming ready: exit status 1", err.Error())
+}
 
-	t.Run("subtest", func(t *testing.T) {
-		// this needs to run in a subtest so that the Runner's
-		// t.Cleanup routine has a chance to run
-		var testTableInput = &dynamodb.CreateTableInput{}
-		var recTableName string
-		ddb.Runner(t, testTableInput, func(_ dynamodbiface.DynamoDBAPI, tableName string) {
-			recTableName = tableName
-		})
-		equals(t, "test_name", recTableName)
-	})
+func TestInitErrorIncludesProcessOutput(t *testing.T) {
+	java := fakeJava(t, `echo "Error: Could not find or load main class" >&2; exit 1`)
 
-	expDeleteTableInput := &dynamodb.DeleteTableInput{
-		TableName: aws.String("test_name"),
+	pcm := &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool { return false },
 	}
-	equals(t, expDeleteTableInput, recDeleteTableInput)
+
+	_, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(pcm),
+	)
+	assert(t, err != nil, "expected an error")
+	assert(t, strings.Contains(err.Error(), "exit status 1"), "unexpected error: %v", err)
+	assert(t, strings.Contains(err.Error(), "Error: Could not find or load main class"), "unexpected error: %v", err)
 }
 
-func TestEmulatorIntegration(t *testing.T) {
-	if !*integration {
+func TestLogsCapturesProcessOutput(t *testing.T) {
+	java := fakeJava(t, `echo "Initializing DynamoDB Local"; exec sleep 10`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+
+	equals(t, "Initializing DynamoDB Local\n", w.String())
+	equals(t, "Initializing DynamoDB Local\n", ddb.Logs())
+}
+
+func TestCloseTerminatesProcessGracefully(t *testing.T) {
+	java := fakeJava(t, `trap 'echo "shutting down"; exit 0' TERM; echo ready; while true; do sleep 0.1; done`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+	)
+	ok(t, err)
+
+	ok(t, ddb.Close())
+	assert(t, strings.Contains(ddb.Logs(), "shutting down"), "expected the process to handle SIGTERM")
+}
+
+func TestCloseKillsProcessAfterGracePeriod(t *testing.T) {
+	java := fakeJava(t, `trap '' TERM; echo ready; while true; do sleep 0.1; done`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomGracePeriod(100*time.Millisecond),
+	)
+	ok(t, err)
+
+	err = ddb.Close()
+	assert(t, err != nil, "expected an error")
+	equals(t, "DynamoDB Local process did not exit within 100ms and was killed", err.Error())
+}
+
+func TestCloseReportsUnexpectedExitStatus(t *testing.T) {
+	java := fakeJava(t, `trap 'exit 3' TERM; echo ready; while true; do sleep 0.1; done`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+	)
+	ok(t, err)
+
+	err = ddb.Close()
+	assert(t, err != nil, "expected an error")
+	equals(t, "DynamoDB Local process exited with an unexpected status: exit status 3", err.Error())
+}
+
+func TestInitFailsIfPortIsTakenByForeignService(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			return ddblocal.ProbeResult{State: ddblocal.PortForeign}
+		},
+	}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+	)
+	assert(t, errors.Is(err, ddblocal.ErrPortInUse), "unexpected error: %v", err)
+	equals(t, "cannot start DynamoDB Local on port 8000: port is in use by a service which isn't DynamoDB compatible", err.Error())
+	equals(t, 0, len(etm.ExecuteCalls()))
+}
+
+func TestInitReportsReusedService(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	res := ddblocal.ProbeResult{State: ddblocal.PortDynamoDB, Vendor: "LocalStack", Version: "3.0.2"}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult { return res },
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+	)
+	ok(t, err)
+	equals(t, res, ddb.Service())
+}
+
+func TestInitRequireExistingReusesInstance(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			return ddblocal.ProbeResult{State: ddblocal.PortDynamoDB}
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+		ddblocal.CustomReusePolicy(ddblocal.RequireExisting),
+	)
+	ok(t, err)
+	equals(t, ddblocal.RequireExisting, ddb.ReusePolicy())
+	equals(t, false, ddb.Owned())
+	equals(t, 0, len(etm.ExecuteCalls()))
+}
+
+func TestInitRequireExistingFailsWhenAbsent(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			return ddblocal.ProbeResult{State: ddblocal.PortFree}
+		},
+	}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+		ddblocal.CustomReusePolicy(ddblocal.RequireExisting),
+	)
+	assert(t, err != nil, "expected an error")
+	equals(t, "no DynamoDB compatible endpoint is running on port 8000", err.Error())
+	equals(t, 0, len(etm.ExecuteCalls()))
+}
+
+func TestInitAlwaysStartFreshPicksAnotherPort(t *testing.T) {
+	t.Parallel()
+
+	var launched int32
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error {
+			atomic.StoreInt32(&launched, 1)
+			return nil
+		},
+	}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			if endpoint == "http://localhost:8000" || atomic.LoadInt32(&launched) == 1 {
+				return ddblocal.ProbeResult{State: ddblocal.PortDynamoDB}
+			}
+			return ddblocal.ProbeResult{State: ddblocal.PortFree}
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+		ddblocal.CustomReusePolicy(ddblocal.AlwaysStartFresh),
+	)
+	ok(t, err)
+	assert(t, ddb.Port() != 8000, "expected another port to be picked")
+	equals(t, true, ddb.Owned())
+	equals(t, 1, len(etm.ExecuteCalls()))
+	assert(t, contains([]string{"-port", strconv.Itoa(ddb.Port())}, etm.ExecuteCalls()[0].Arg), "expected DynamoDB Local to be started on the picked port")
+}
+
+func TestInitAlwaysStartFreshReplacesTakenRandomPort(t *testing.T) {
+	t.Parallel()
+
+	var launched, probed int32
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error {
+			atomic.StoreInt32(&launched, 1)
+			return nil
+		},
+	}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			// the random port is taken before the first probe
+			if atomic.AddInt32(&probed, 1) == 1 || atomic.LoadInt32(&launched) == 1 {
+				return ddblocal.ProbeResult{State: ddblocal.PortDynamoDB}
+			}
+			return ddblocal.ProbeResult{State: ddblocal.PortFree}
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+		ddblocal.RandomPort(),
+		ddblocal.CustomReusePolicy(ddblocal.AlwaysStartFresh),
+	)
+	ok(t, err)
+	equals(t, true, ddb.Owned())
+	equals(t, 1, len(etm.ExecuteCalls()))
+	assert(t, contains([]string{"-port", strconv.Itoa(ddb.Port())}, etm.ExecuteCalls()[0].Arg), "expected DynamoDB Local to be started on the picked port")
+}
+
+func TestInitAlwaysStartFreshFailsOnTakenExplicitPort(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			return ddblocal.ProbeResult{State: ddblocal.PortDynamoDB}
+		},
+	}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomProber(pm),
+		ddblocal.CustomPort(8123),
+		ddblocal.CustomReusePolicy(ddblocal.AlwaysStartFresh),
+	)
+	assert(t, err != nil, "expected an error")
+	equals(t, "cannot start DynamoDB Local on port 8123: port is already in use", err.Error())
+	equals(t, 0, len(etm.ExecuteCalls()))
+}
+
+func TestInitCustomEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(dynamoDBHandler(nil))
+	defer ts.Close()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	cim := &mocks.ClientInitializerContextMock{
+		InitClientContextFunc: func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+			return nil, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomClientInitializerContext(cim),
+		ddblocal.CustomEndpoint(ts.URL),
+	)
+	ok(t, err)
+
+	port, err := testServerPort(ts.URL)
+	ok(t, err)
+	equals(t, ts.URL, ddb.Endpoint())
+	equals(t, port, ddb.Port())
+	equals(t, false, ddb.Owned())
+	equals(t, ts.URL, cim.InitClientContextCalls()[0].Endpoint)
+
+	ok(t, ddb.Close())
+	equals(t, 0, len(etm.ExecuteCalls()))
+	equals(t, 0, len(etm.TerminateCalls()))
+}
+
+func TestInitCustomEndpointFailsWhenAbsent(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	_, err := ddblocal.New(
+		ddblocal.CustomEndpoint(ts.URL),
+		ddblocal.CustomStartupTimeout(100*time.Millisecond),
+	)
+	assert(t, err != nil, "expected an error")
+	equals(t, "no DynamoDB compatible endpoint is running at "+ts.URL, err.Error())
+}
+
+func TestInitCustomEndpointWaitsForEndpoint(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	pm := &mocks.ProberMock{
+		ProbeFunc: func(ctx context.Context, endpoint string) ddblocal.ProbeResult {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				return ddblocal.ProbeResult{State: ddblocal.PortFree}
+			}
+			return ddblocal.ProbeResult{State: ddblocal.PortDynamoDB}
+		},
+	}
+	cim := &mocks.ClientInitializerContextMock{
+		InitClientContextFunc: func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+			return nil, nil
+		},
+	}
+
+	_, err := ddblocal.New(
+		ddblocal.CustomProber(pm),
+		ddblocal.CustomClientInitializerContext(cim),
+		ddblocal.CustomEndpoint("http://dynamodb:8000"),
+		ddblocal.CustomStartupBackoff(time.Millisecond, time.Millisecond),
+	)
+	ok(t, err)
+	equals(t, 3, len(pm.ProbeCalls()))
+}
+
+func TestInitCustomEndpointRejectsInvalidURL(t *testing.T) {
+	t.Parallel()
+
+	_, err := ddblocal.New(ddblocal.CustomEndpoint("localhost:8000"))
+	assert(t, err != nil, "expected an error")
+	equals(t, `invalid endpoint: "localhost:8000"`, err.Error())
+}
+
+func TestInitWithContextPropagatesContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	fromCtx := func(ctx context.Context) interface{} { return ctx.Value(key{}) }
+
+	etm := &mocks.ExecutorTerminatorContextMock{
+		ExecuteContextFunc:   func(ctx context.Context, name string, arg ...string) error { return nil },
+		TerminateContextFunc: func(ctx context.Context) error { return nil },
+	}
+	var calls int32
+	pcm := &mocks.PresenceCheckerContextMock{
+		IsPresentContextFunc: func(ctx context.Context, port int) bool {
+			return atomic.AddInt32(&calls, 1) > 1
+		},
+	}
+	cim := &mocks.ClientInitializerContextMock{
+		InitClientContextFunc: func(ctx context.Context, endpoint string) (dynamodbiface.DynamoDBAPI, error) {
+			return nil, nil
+		},
+	}
+
+	ddb, err := ddblocal.NewWithContext(ctx,
+		ddblocal.CustomExecutorTerminatorContext(etm),
+		ddblocal.CustomPresenceCheckerContext(pcm),
+		ddblocal.CustomClientInitializerContext(cim),
+	)
+	ok(t, err)
+
+	equals(t, "value", fromCtx(etm.ExecuteContextCalls()[0].Ctx))
+	equals(t, "value", fromCtx(pcm.IsPresentContextCalls()[1].Ctx))
+	equals(t, "value", fromCtx(cim.InitClientContextCalls()[0].Ctx))
+
+	ok(t, ddb.CloseContext(ctx))
+	equals(t, "value", fromCtx(etm.TerminateContextCalls()[0].Ctx))
+}
+
+func TestInitWithContextGivesUpWhenDone(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	pcm := &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool { return false },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := ddblocal.NewWithContext(ctx,
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomStartupTimeout(time.Minute),
+		ddblocal.CustomStartupBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+	assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	equals(t, 1, len(etm.TerminateCalls()))
+}
+
+func TestCloseContextKillsProcessWhenDone(t *testing.T) {
+	java := fakeJava(t, `trap '' TERM; echo ready; while true; do sleep 0.1; done`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomGracePeriod(time.Minute),
+	)
+	ok(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = ddb.CloseContext(ctx)
+	assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+}
+
+func TestRunnerCreatesTableCorrectly(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+
+	pcm := startingPresenceChecker()
+
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return "test_name", nil
+		},
+	}
+
+	var recCreateTableInput *dynamodb.CreateTableInput
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			recCreateTableInput = in1
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+	)
+	ok(t, err)
+
+	var recTableName string
+	tableInput := &dynamodb.CreateTableInput{
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{IndexName: aws.String("GSI")},
+		},
+	}
+	ddb.Runner(t, tableInput, func(_ dynamodbiface.DynamoDBAPI, tableName string) {
+		recTableName = tableName
+	})
+	equals(t, "test_name", recTableName)
+
+	expCreateTableInput := &dynamodb.CreateTableInput{
+		TableName: aws.String("test_name"),
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(1),
+			WriteCapacityUnits: aws.Int64(1),
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("GSI"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(1),
+					WriteCapacityUnits: aws.Int64(1),
+				},
+			},
+		},
+	}
+	equals(t, expCreateTableInput, recCreateTableInput)
+}
+
+func TestRunnerDeletesTableCorrectly(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+
+	pcm := startingPresenceChecker()
+
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return "test_name", nil
+		},
+	}
+
+	var recDeleteTableInput *dynamodb.DeleteTableInput
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			recDeleteTableInput = in1
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(pcm),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+	)
+	ok(t, err)
+
+	t.Run("subtest", func(t *testing.T) {
+		// this needs to run in a subtest so that the Runner's
+		// t.Cleanup routine has a chance to run
+		var testTableInput = &dynamodb.CreateTableInput{}
+		var recTableName string
+		ddb.Runner(t, testTableInput, func(_ dynamodbiface.DynamoDBAPI, tableName string) {
+			recTableName = tableName
+		})
+		equals(t, "test_name", recTableName)
+	})
+
+	expDeleteTableInput := &dynamodb.DeleteTableInput{
+		TableName: aws.String("test_name"),
+	}
+	equals(t, expDeleteTableInput, recDeleteTableInput)
+}
+
+func TestRunnerDoesNotModifyTableDef(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomClientInitializer(cim),
+	)
+	ok(t, err)
+
+	tableInput := &dynamodb.CreateTableInput{
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{IndexName: aws.String("GSI")},
+		},
+	}
+	ddb.Runner(t, tableInput, func(dynamodbiface.DynamoDBAPI, string) {})
+
+	expTableInput := &dynamodb.CreateTableInput{
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{IndexName: aws.String("GSI")},
+		},
+	}
+	equals(t, expTableInput, tableInput)
+}
+
+func TestMultiTableRunnerCreatesAndDeletesTables(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	var generated int32
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return fmt.Sprintf("table_%d", atomic.AddInt32(&generated, 1)), nil
+		},
+	}
+	var created, deleted []string
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			created = append(created, *in1.TableName)
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			deleted = append(deleted, *in1.TableName)
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+	)
+	ok(t, err)
+
+	var recTableNames map[string]string
+	t.Run("subtest", func(t *testing.T) {
+		// this needs to run in a subtest so that the Runner's
+		// t.Cleanup routine has a chance to run
+		ddb.MultiTableRunner(t, map[string]*dynamodb.CreateTableInput{
+			"users":  {},
+			"orders": {},
+		}, func(_ dynamodbiface.DynamoDBAPI, tableNames map[string]string) {
+			recTableNames = tableNames
+		})
+	})
+
+	equals(t, map[string]string{"orders": "table_1", "users": "table_2"}, recTableNames)
+	equals(t, []string{"table_1", "table_2"}, created)
+	equals(t, []string{"table_2", "table_1"}, deleted)
+}
+
+func TestRunnerDerivesTableNamesFromTestName(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return "abc123", nil
+		},
+	}
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+		ddblocal.CustomTableNamePrefix("app"),
+	)
+	ok(t, err)
+
+	runnerTableName := func(t *testing.T) string {
+		var recTableName string
+		ddb.Runner(t, &dynamodb.CreateTableInput{}, func(_ dynamodbiface.DynamoDBAPI, tableName string) {
+			recTableName = tableName
+		})
+		return recTableName
+	}
+
+	t.Run("sub test/with:chars", func(t *testing.T) {
+		equals(t, "app-TestRunnerDerivesTableNamesFromTestName_sub_test_with_chars-abc123", runnerTableName(t))
+	})
+
+	t.Run(strings.Repeat("a", 300), func(t *testing.T) {
+		tableName := runnerTableName(t)
+		equals(t, 255, len(tableName))
+		assert(t, strings.HasPrefix(tableName, "app-TestRunnerDerivesTableNamesFromTestName_aaa"), "unexpected table name: %s", tableName)
+		assert(t, strings.HasSuffix(tableName, "a-abc123"), "unexpected table name: %s", tableName)
+	})
+}
+
+func TestRunnerWaitsForTableAndIndexesToBecomeActive(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	// the table is described as CREATING, then ACTIVE with the index still
+	// CREATING, then fully ACTIVE, then DELETING and finally not found
+	statuses := []struct{ table, index string }{
+		{dynamodb.TableStatusCreating, dynamodb.IndexStatusCreating},
+		{dynamodb.TableStatusActive, dynamodb.IndexStatusCreating},
+		{dynamodb.TableStatusActive, dynamodb.IndexStatusActive},
+		{dynamodb.TableStatusDeleting, dynamodb.IndexStatusDeleting},
+	}
+	var described int
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return &dynamodb.CreateTableOutput{}, nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: func(ctx context.Context, in1 *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+			if described == len(statuses) {
+				return tableNotFound(ctx, in1, opts...)
+			}
+			status := statuses[described]
+			described++
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableName:   in1.TableName,
+				TableStatus: aws.String(status.table),
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{IndexName: aws.String("GSI"), IndexStatus: aws.String(status.index)},
+				},
+			}}, nil
+		},
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomClientInitializer(cim),
+	)
+	ok(t, err)
+
+	var describedBeforeRun int
+	t.Run("subtest", func(t *testing.T) {
+		// this needs to run in a subtest so that the Runner's
+		// t.Cleanup routine has a chance to run
+		ddb.Runner(t, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {
+			describedBeforeRun = described
+		})
+	})
+
+	equals(t, 3, describedBeforeRun)
+	equals(t, 5, len(ddbcm.DescribeTableWithContextCalls()))
+}
+
+func TestRunnerFailsWhenTableDoesNotBecomeActive(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return "test_name", nil
+		},
+	}
+	var deleted int32
+	ddbcm := &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return &dynamodb.CreateTableOutput{}, nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			atomic.StoreInt32(&deleted, 1)
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: func(ctx context.Context, in1 *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+			if atomic.LoadInt32(&deleted) == 1 {
+				return tableNotFound(ctx, in1, opts...)
+			}
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableName:   in1.TableName,
+				TableStatus: aws.String(dynamodb.TableStatusCreating),
+			}}, nil
+		},
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+		ddblocal.CustomTableTimeout(100*time.Millisecond),
+	)
+	ok(t, err)
+
+	var called bool
+	msg := recordFatal(t, func(tb testing.TB) {
+		ddb.Runner(tb, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {
+			called = true
+		})
+	})
+	equals(t, "failed to create table: table test_name did not become ACTIVE within 100ms: context deadline exceeded", msg)
+	equals(t, false, called)
+}
+
+func TestEmulatorIntegration(t *testing.T) {
+	if !*integration {
 		t.SkipNow()
 	}
 
@@ -381,6 +1401,179 @@ func TestEmulatorIntegration(t *testing.T) {
 	})
 }
 
+// monitoredExecutorTerminator is an ExecutorTerminator which also implements
+// the ProcessMonitor interface.
+type monitoredExecutorTerminator struct {
+	*mocks.ExecutorTerminatorMock
+	*mocks.ProcessMonitorMock
+}
+
+// fakeExecutable installs a shell script with the given name and body in a
+// temporary directory which is prepended to PATH for the duration of the test.
+func fakeExecutable(t *testing.T, name, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" + body + "\n"
+	ok(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755))
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() {
+		os.Setenv("PATH", path)
+	})
+}
+
+// fakeJava returns an option configuring the Emulator to use a fake Java
+// runtime, which runs the given shell script body, and a fake DynamoDB Local
+// distribution.
+func fakeJava(t *testing.T, body string) ddblocal.EmulatorOption {
+	t.Helper()
+	javaPath := filepath.Join(fakeJavaHome(t, `openjdk version "17.0.2" 2022-01-18`, body), "bin", "java")
+	distribution := fakeDistribution(t)
+	return func(e *ddblocal.Emulator) {
+		ddblocal.CustomJavaPath(javaPath)(e)
+		distribution(e)
+	}
+}
+
+// fakeJavaHome creates a directory with a fake Java runtime in its bin
+// subdirectory, which reports the given version string and otherwise runs the
+// given shell script body.
+func fakeJavaHome(t *testing.T, version, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	ok(t, os.Mkdir(filepath.Join(dir, "bin"), 0755))
+	script := fmt.Sprintf("#!/bin/sh\nif [ \"$1\" = \"-version\" ]; then echo '%s' >&2; exit 0; fi\n%s\n", version, body)
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "bin", "java"), []byte(script), 0755))
+	return dir
+}
+
+// fakeDistribution returns an option configuring the Emulator to use a fake
+// DynamoDB Local jar and library directory, and to record the processes it
+// launches in a temporary directory.
+func fakeDistribution(t *testing.T) ddblocal.EmulatorOption {
+	t.Helper()
+	dir := t.TempDir()
+	jarPath := filepath.Join(dir, "DynamoDBLocal.jar")
+	libPath := filepath.Join(dir, "DynamoDBLocal_lib")
+	ok(t, ioutil.WriteFile(jarPath, nil, 0644))
+	ok(t, os.Mkdir(libPath, 0755))
+	pidDir := t.TempDir()
+	return func(e *ddblocal.Emulator) {
+		ddblocal.CustomJarPath(jarPath)(e)
+		ddblocal.CustomLibPath(libPath)(e)
+		ddblocal.CustomPidDir(pidDir)(e)
+	}
+}
+
+// syncBuffer is a bytes.Buffer which is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// outputPresenceChecker returns a PresenceChecker mock which reports the
+// emulator as present once the process has written something to w.
+func outputPresenceChecker(w *syncBuffer) *mocks.PresenceCheckerMock {
+	return &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool { return w.Len() > 0 },
+	}
+}
+
+// fatalRecorder is a testing.TB which records the message passed to Fatalf
+// instead of failing the test.
+type fatalRecorder struct {
+	testing.TB
+	msg string
+}
+
+func (r *fatalRecorder) Fatalf(format string, args ...interface{}) {
+	r.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// recordFatal calls f with a fatalRecorder and returns the message passed to
+// its Fatalf method, if any.
+func recordFatal(tb testing.TB, f func(tb testing.TB)) string {
+	r := &fatalRecorder{TB: tb}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.msg
+}
+
+// activeTable returns the output of CreateTable reporting the table as
+// ACTIVE.
+func activeTable(in *dynamodb.CreateTableInput) *dynamodb.CreateTableOutput {
+	return &dynamodb.CreateTableOutput{TableDescription: &dynamodb.TableDescription{
+		TableName:   in.TableName,
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+	}}
+}
+
+// tableNotFound reports every table as not found, as if it was deleted.
+func tableNotFound(_ context.Context, in *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Cannot do operations on a non-existent table", nil)
+}
+
+// newRunnerEmulator returns an Emulator started with mocks, which uses ddbcm
+// as its client and names every table test_name.
+func newRunnerEmulator(t *testing.T, ddbcm *mocks.DynamoDBAPIMock, options ...ddblocal.EmulatorOption) *ddblocal.Emulator {
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error { return nil },
+	}
+	sgm := &mocks.StringGeneratorMock{
+		GenerateFunc: func() (string, error) {
+			return "test_name", nil
+		},
+	}
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			return ddbcm, nil
+		},
+	}
+	ddb, err := ddblocal.New(append([]ddblocal.EmulatorOption{
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomStringGenerator(sgm),
+		ddblocal.CustomClientInitializer(cim),
+	}, options...)...)
+	ok(t, err)
+	return ddb
+}
+
+// startingPresenceChecker returns a PresenceChecker mock which reports the
+// emulator as absent on the first call and as present afterwards, as if the
+// process was started by the Emulator.
+func startingPresenceChecker() *mocks.PresenceCheckerMock {
+	var calls int32
+	return &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool {
+			return atomic.AddInt32(&calls, 1) > 1
+		},
+	}
+}
+
 // ok fails the test if an err is not nil.
 func ok(tb testing.TB, err error) {
 	if err != nil {
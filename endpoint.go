@@ -0,0 +1,32 @@
+package ddblocal
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// localEndpoint returns the URL of DynamoDB Local listening on the port on
+// localhost.
+func localEndpoint(port int) string {
+	return fmt.Sprintf("http://localhost:%d", port)
+}
+
+// endpointPort returns the port of the endpoint URL, which defaults to the
+// one of its scheme.
+func endpointPort(endpoint string) (int, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return 0, fmt.Errorf("invalid endpoint: %q", endpoint)
+	}
+	if p := u.Port(); p != "" {
+		return strconv.Atoi(p)
+	}
+	if u.Scheme == "https" {
+		return 443, nil
+	}
+	return 80, nil
+}
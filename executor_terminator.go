@@ -1,33 +1,131 @@
 package ddblocal
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os/exec"
+	"time"
 )
 
 type executorTerminator struct {
-	instance *exec.Cmd
+	instance    *exec.Cmd
+	output      io.Writer
+	gracePeriod time.Duration
+	detached    bool
+	done        chan struct{}
+	err         error
 }
 
 func (e *executorTerminator) Execute(name string, arg ...string) error {
+	return e.ExecuteContext(context.Background(), name, arg...)
+}
+
+// ExecuteContext starts the process unless ctx is already done. The context
+// doesn't bound the lifetime of the started process.
+func (e *executorTerminator) ExecuteContext(ctx context.Context, name string, arg ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	cmd := exec.Command(name, arg...)
-	if err := cmd.Start(); err != nil {
+	cmd.Stdout = e.output
+	cmd.Stderr = e.output
+	setProcessGroup(cmd)
+	start := cmd.Start
+	if !e.detached {
+		start = tieToParent(cmd)
+	}
+	if err := start(); err != nil {
 		return err
 	}
 	e.instance = cmd
+	e.done = make(chan struct{})
+	go func(done chan struct{}) {
+		e.err = cmd.Wait()
+		close(done)
+	}(e.done)
 	return nil
 }
 
+// Terminate asks the process to exit and waits for it for the duration of the
+// grace period, after which the whole process group is killed. An error is
+// returned if the process exited before being asked to with a non-zero status,
+// exited with an unexpected status, or had to be killed.
 func (e *executorTerminator) Terminate() error {
+	return e.TerminateContext(context.Background())
+}
+
+// TerminateContext works like Terminate, but the process group is killed
+// right away if ctx is done before the process exits.
+func (e *executorTerminator) TerminateContext(ctx context.Context) error {
 	if e.instance == nil {
 		return nil
 	}
-	if err := e.instance.Process.Kill(); err != nil {
+	defer func() {
+		e.instance = nil
+	}()
+
+	select {
+	case <-e.done:
+		if e.err != nil {
+			return fmt.Errorf("DynamoDB Local process exited unexpectedly: %w", e.err)
+		}
+		return nil
+	default:
+	}
+
+	if err := interruptProcessGroup(e.instance.Process.Pid); err != nil {
 		return err
 	}
-	e.instance = nil
-	return nil
+
+	timer := time.NewTimer(e.gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-e.done:
+		if e.err != nil && !isInterruptExit(e.err) {
+			return fmt.Errorf("DynamoDB Local process exited with an unexpected status: %w", e.err)
+		}
+		return nil
+	case <-ctx.Done():
+		if err := killProcessGroup(e.instance.Process.Pid); err != nil {
+			return err
+		}
+		<-e.done
+		return fmt.Errorf("DynamoDB Local process was killed before exiting: %w", ctx.Err())
+	case <-timer.C:
+		if err := killProcessGroup(e.instance.Process.Pid); err != nil {
+			return err
+		}
+		<-e.done
+		return fmt.Errorf("DynamoDB Local process did not exit within %s and was killed", e.gracePeriod)
+	}
+}
+
+// Done returns a channel that is closed when the executed process exits.
+func (e *executorTerminator) Done() <-chan struct{} {
+	return e.done
+}
+
+// Err returns the error with which the executed process exited.
+func (e *executorTerminator) Err() error {
+	return e.err
+}
+
+// Pid returns the process ID of the executed process.
+func (e *executorTerminator) Pid() int {
+	if e.instance == nil {
+		return 0
+	}
+	return e.instance.Process.Pid
+}
+
+func newExecutorTerminator(output io.Writer, gracePeriod time.Duration) *executorTerminator {
+	return &executorTerminator{output: output, gracePeriod: gracePeriod}
 }
 
+// NewExecutorTerminator returns a new instance of ExecutorTerminator with
+// default configuration.
 func NewExecutorTerminator() ExecutorTerminator {
-	return &executorTerminator{}
+	return newExecutorTerminator(nil, defaultGracePeriod)
 }
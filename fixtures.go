@@ -0,0 +1,314 @@
+package ddblocal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// maxBatchWriteItems is the number of items BatchWriteItem accepts in a
+	// single request.
+	maxBatchWriteItems = 25
+
+	// maxBatchWriteAttempts bounds the number of times unprocessed items are
+	// retried.
+	maxBatchWriteAttempts = 10
+)
+
+// runnerOptions is the configuration of a single Runner call.
+type runnerOptions struct {
+	fixtures      []string
+	tableFixtures map[string][]string
+}
+
+// RunnerOption is a unit of Runner configuration.
+type RunnerOption func(*runnerOptions)
+
+// Fixtures makes the Runner load the items from the given files, or from the
+// .json, .yaml and .yml files in the given directories, into the table before
+// running the test.
+//
+// A file holds a single item or a list of items. JSON items in which every
+// attribute is a DynamoDB JSON attribute value, like {"id": {"S": "1"}}, are
+// read as DynamoDB JSON. Other JSON and all YAML items are plain values
+// marshalled with dynamodbattribute. A JSON file holds a single JSON value.
+//
+// Fixtures can't be used with MultiTableRunner, which takes TableFixtures
+// instead.
+func Fixtures(paths ...string) RunnerOption {
+	return func(o *runnerOptions) {
+		o.fixtures = append(o.fixtures, paths...)
+	}
+}
+
+// TableFixtures works like Fixtures for the table with the given logical name
+// created by MultiTableRunner.
+func TableFixtures(name string, paths ...string) RunnerOption {
+	return func(o *runnerOptions) {
+		if o.tableFixtures == nil {
+			o.tableFixtures = make(map[string][]string)
+		}
+		o.tableFixtures[name] = append(o.tableFixtures[name], paths...)
+	}
+}
+
+// readFixtures reads the items from the fixture files and directories.
+func readFixtures(paths []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fileItems, err := readFixtureFile(file)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, fileItems...)
+		}
+	}
+	return items, nil
+}
+
+// fixtureFiles returns the path if it's a file, or the fixture files in it, in
+// lexical order, if it's a directory.
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".json", ".yaml", ".yml":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// readFixtureFile reads the items from a single fixture file.
+func readFixtureFile(path string) ([]map[string]*dynamodb.AttributeValue, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	switch filepath.Ext(path) {
+	case ".json":
+		values, err = decodeJSONFixture(data)
+	case ".yaml", ".yml":
+		values, err = decodeYAMLFixture(data)
+	default:
+		return nil, fmt.Errorf("unsupported fixture file: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture file %s: %w", path, err)
+	}
+
+	// YAML has no DynamoDB JSON counterpart
+	dynamoDBJSON := filepath.Ext(path) == ".json"
+	items := make([]map[string]*dynamodb.AttributeValue, 0, len(values))
+	for _, value := range values {
+		item, err := fixtureItem(value, dynamoDBJSON)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture file %s: %w", path, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeJSONFixture(data []byte) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// keeps the exact representation of numbers
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected content after the first JSON value")
+	}
+	return fixtureValues(v)
+}
+
+func decodeYAMLFixture(data []byte) ([]interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return fixtureValues(stringKeys(v))
+}
+
+// fixtureValues returns the items of a fixture holding a single item or a list
+// of items.
+func fixtureValues(v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return []interface{}{v}, nil
+	case []interface{}:
+		return v, nil
+	default:
+		return nil, errors.New("expected an item or a list of items")
+	}
+}
+
+// stringKeys converts the maps decoded from YAML into maps with string keys,
+// which can be marshalled with dynamodbattribute.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// fixtureItem converts a decoded item to attribute values, reading it as
+// DynamoDB JSON if it may be DynamoDB JSON and every attribute is an attribute
+// value.
+func fixtureItem(v interface{}, dynamoDBJSON bool) (map[string]*dynamodb.AttributeValue, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an item, got %T", v)
+	}
+	if dynamoDBJSON && isDynamoDBJSON(m) {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var item map[string]*dynamodb.AttributeValue
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+	return dynamodbattribute.MarshalMap(numbers(m))
+}
+
+// isDynamoDBJSON reports whether every attribute of the item is an object
+// with a single attribute value type descriptor.
+func isDynamoDBJSON(item map[string]interface{}) bool {
+	if len(item) == 0 {
+		return false
+	}
+	for _, v := range item {
+		av, ok := v.(map[string]interface{})
+		if !ok || len(av) != 1 {
+			return false
+		}
+		for k := range av {
+			switch k {
+			case "S", "N", "B", "SS", "NS", "BS", "M", "L", "NULL", "BOOL":
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// numbers converts the JSON numbers in v into numbers which dynamodbattribute
+// marshals as such.
+func numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return dynamodbattribute.Number(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// loadFixtures writes the items to the table in batches.
+func (e *Emulator) loadFixtures(ctx context.Context, tableName string, items []map[string]*dynamodb.AttributeValue) error {
+	for start := 0; start < len(items); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(items) {
+			end = len(items)
+		}
+		requests := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, item := range items[start:end] {
+			requests = append(requests, &dynamodb.WriteRequest{
+				PutRequest: &dynamodb.PutRequest{Item: item},
+			})
+		}
+		if err := e.batchWrite(ctx, tableName, requests); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchWrite writes a batch of items, retrying the unprocessed items with an
+// exponential backoff.
+func (e *Emulator) batchWrite(ctx context.Context, tableName string, requests []*dynamodb.WriteRequest) error {
+	interval := tablePollInterval
+	for attempt := 1; ; attempt++ {
+		out, err := e.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{tableName: requests},
+		})
+		if err != nil {
+			return err
+		}
+		if out == nil || len(out.UnprocessedItems[tableName]) == 0 {
+			return nil
+		}
+		requests = out.UnprocessedItems[tableName]
+		if attempt == maxBatchWriteAttempts {
+			return fmt.Errorf("%d items remained unprocessed after %d attempts", len(requests), attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d items remained unprocessed: %w", len(requests), ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > tableMaxPollInterval {
+			interval = tableMaxPollInterval
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package ddblocal_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+// fixtureMocks returns a DynamoDBAPI mock which records the written items,
+// leaving the first item of every batch unprocessed until it's retried.
+func fixtureMocks(written *[]map[string]*dynamodb.AttributeValue) *mocks.DynamoDBAPIMock {
+	retried := make(map[*dynamodb.WriteRequest]bool)
+	return &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, nil
+		},
+		DescribeTableWithContextFunc: tableNotFound,
+		BatchWriteItemWithContextFunc: func(_ context.Context, in1 *dynamodb.BatchWriteItemInput, _ ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+			requests := in1.RequestItems["test_name"]
+			if first := requests[0]; !retried[first] {
+				retried[first] = true
+				for _, r := range requests[1:] {
+					*written = append(*written, r.PutRequest.Item)
+				}
+				return &dynamodb.BatchWriteItemOutput{
+					UnprocessedItems: map[string][]*dynamodb.WriteRequest{"test_name": requests[:1]},
+				}, nil
+			}
+			for _, r := range requests {
+				*written = append(*written, r.PutRequest.Item)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+}
+
+func writeFixture(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	ok(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestRunnerLoadsFixtures(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFixture(t, dir, "1_dynamodb.json", `[
+		{"id": {"S": "1"}, "count": {"N": "10"}, "tags": {"SS": ["a", "b"]}},
+		{"id": {"S": "2"}, "active": {"BOOL": true}}
+	]`)
+	writeFixture(t, dir, "2_plain.json", `{"id": "3", "count": 12345678901234567890, "nested": {"S": "not an attribute value"}}`)
+	writeFixture(t, dir, "3_plain.yaml", "- id: \"4\"\n  labels:\n    env: test\n- name:\n    S: not an attribute value\n")
+	writeFixture(t, dir, "README.md", "not a fixture")
+
+	var written []map[string]*dynamodb.AttributeValue
+	ddbcm := fixtureMocks(&written)
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	var called bool
+	ddb.Runner(t, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {
+		called = true
+		equals(t, 2, len(ddbcm.BatchWriteItemWithContextCalls()))
+	}, ddblocal.Fixtures(dir))
+	equals(t, true, called)
+
+	equals(t, []map[string]*dynamodb.AttributeValue{
+		{"id": {S: aws.String("2")}, "active": {BOOL: aws.Bool(true)}},
+		{
+			"id":     {S: aws.String("3")},
+			"count":  {N: aws.String("12345678901234567890")},
+			"nested": {M: map[string]*dynamodb.AttributeValue{"S": {S: aws.String("not an attribute value")}}},
+		},
+		{"id": {S: aws.String("4")}, "labels": {M: map[string]*dynamodb.AttributeValue{"env": {S: aws.String("test")}}}},
+		{"name": {M: map[string]*dynamodb.AttributeValue{"S": {S: aws.String("not an attribute value")}}}},
+		{"id": {S: aws.String("1")}, "count": {N: aws.String("10")}, "tags": {SS: aws.StringSlice([]string{"a", "b"})}},
+	}, written)
+}
+
+func TestRunnerLoadsFixturesInBatches(t *testing.T) {
+	t.Parallel()
+
+	var items []string
+	for i := 0; i < 30; i++ {
+		items = append(items, fmt.Sprintf(`{"id": "%d"}`, i))
+	}
+	path := writeFixture(t, t.TempDir(), "items.json", "["+strings.Join(items, ",")+"]")
+
+	var written []map[string]*dynamodb.AttributeValue
+	ddbcm := fixtureMocks(&written)
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	ddb.Runner(t, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {}, ddblocal.Fixtures(path))
+
+	calls := ddbcm.BatchWriteItemWithContextCalls()
+	equals(t, 4, len(calls))
+	equals(t, 25, len(calls[0].In2.RequestItems["test_name"]))
+	equals(t, 5, len(calls[2].In2.RequestItems["test_name"]))
+	equals(t, 30, len(written))
+}
+
+func TestRunnerFailsOnInvalidFixtures(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name string
+		path string
+		msg  string
+	}{
+		{
+			name: "unsupported",
+			path: writeFixture(t, dir, "items.csv", "id\n1\n"),
+			msg:  "failed to read fixtures: unsupported fixture file: " + filepath.Join(dir, "items.csv"),
+		},
+		{
+			name: "not an item",
+			path: writeFixture(t, dir, "items.json", `["1"]`),
+			msg:  "failed to read fixtures: invalid fixture file " + filepath.Join(dir, "items.json") + ": expected an item, got string",
+		},
+		{
+			name: "several values",
+			path: writeFixture(t, dir, "items.ndjson.json", "{\"id\": \"1\"}\n{\"id\": \"2\"}\n"),
+			msg:  "failed to read fixtures: invalid fixture file " + filepath.Join(dir, "items.ndjson.json") + ": unexpected content after the first JSON value",
+		},
+		{
+			name: "missing",
+			path: filepath.Join(dir, "missing.json"),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var written []map[string]*dynamodb.AttributeValue
+			ddbcm := fixtureMocks(&written)
+			ddb := newRunnerEmulator(t, ddbcm)
+
+			msg := recordFatal(t, func(tb testing.TB) {
+				ddb.Runner(tb, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {}, ddblocal.Fixtures(tc.path))
+			})
+			if tc.msg != "" {
+				equals(t, tc.msg, msg)
+			} else {
+				assert(t, strings.HasPrefix(msg, "failed to read fixtures: ") && strings.Contains(msg, tc.path), "unexpected message: %s", msg)
+			}
+			equals(t, 0, len(ddbcm.CreateTableWithContextCalls()))
+		})
+	}
+}
+
+func TestMultiTableRunnerLoadsTableFixtures(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	users := writeFixture(t, dir, "users.json", `[{"id": "1"}, {"id": "2"}]`)
+	orders := writeFixture(t, dir, "orders.yaml", "id: \"3\"\n")
+
+	var written []map[string]*dynamodb.AttributeValue
+	ddbcm := fixtureMocks(&written)
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	ddb.MultiTableRunner(t, map[string]*dynamodb.CreateTableInput{
+		"users":  {},
+		"orders": {},
+		"empty":  {},
+	}, func(dynamodbiface.DynamoDBAPI, map[string]string) {}, ddblocal.TableFixtures("users", users), ddblocal.TableFixtures("orders", orders))
+
+	equals(t, 3, len(ddbcm.CreateTableWithContextCalls()))
+	equals(t, []map[string]*dynamodb.AttributeValue{
+		{"id": {S: aws.String("3")}},
+		{"id": {S: aws.String("2")}},
+		{"id": {S: aws.String("1")}},
+	}, written)
+}
+
+func TestRunnerRejectsFixturesOfTheOtherRunner(t *testing.T) {
+	t.Parallel()
+
+	path := writeFixture(t, t.TempDir(), "items.json", `{"id": "1"}`)
+
+	var written []map[string]*dynamodb.AttributeValue
+	ddbcm := fixtureMocks(&written)
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	msg := recordFatal(t, func(tb testing.TB) {
+		ddb.Runner(tb, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {}, ddblocal.TableFixtures("users", path))
+	})
+	equals(t, "TableFixtures can only be used with MultiTableRunner, use Fixtures instead", msg)
+
+	msg = recordFatal(t, func(tb testing.TB) {
+		ddb.MultiTableRunner(tb, map[string]*dynamodb.CreateTableInput{"users": {}}, func(dynamodbiface.DynamoDBAPI, map[string]string) {}, ddblocal.Fixtures(path))
+	})
+	equals(t, "Fixtures can't be used with MultiTableRunner, use TableFixtures instead", msg)
+
+	msg = recordFatal(t, func(tb testing.TB) {
+		ddb.MultiTableRunner(tb, map[string]*dynamodb.CreateTableInput{"users": {}}, func(dynamodbiface.DynamoDBAPI, map[string]string) {}, ddblocal.TableFixtures("orders", path))
+	})
+	equals(t, `failed to read fixtures: no table named "orders"`, msg)
+	equals(t, 0, len(ddbcm.CreateTableWithContextCalls()))
+}
@@ -2,4 +2,7 @@ module github.com/fwojciec/ddblocal
 
 go 1.15
 
-require github.com/aws/aws-sdk-go v1.36.19
+require (
+	github.com/aws/aws-sdk-go v1.36.19
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,276 @@
+package ddblocal
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInstallBaseURL = "https://s3.us-west-2.amazonaws.com/dynamodb-local"
+	defaultInstallVersion = "latest"
+
+	installJarName = "DynamoDBLocal.jar"
+	installLibName = "DynamoDBLocal_lib"
+
+	// latestMaxAge is how long the "latest" release is used before it's
+	// downloaded again, to pick up newer releases.
+	latestMaxAge = 7 * 24 * time.Hour
+)
+
+type installer struct {
+	client   *http.Client
+	baseURL  string
+	version  string
+	checksum string
+	cacheDir string
+}
+
+// Install makes sure the configured release of DynamoDB Local is present in
+// the cache directory, downloading, verifying and unpacking it if necessary,
+// and returns the paths of its jar and native library directory. The "latest"
+// release is downloaded again once its cached copy is a week old.
+func (i *installer) Install() (string, string, error) {
+	cacheDir := i.cacheDir
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to determine the cache directory: %w", err)
+		}
+		cacheDir = filepath.Join(userCacheDir, "ddblocal")
+	}
+
+	dir := filepath.Join(cacheDir, i.version)
+	jarPath := filepath.Join(dir, installJarName)
+	libPath := filepath.Join(dir, installLibName)
+	fi, err := os.Stat(jarPath)
+	stale := err == nil && i.version == defaultInstallVersion && time.Since(fi.ModTime()) > latestMaxAge
+	if err == nil && !stale {
+		return jarPath, libPath, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", "", err
+	}
+	tmpDir, err := ioutil.TempDir(cacheDir, i.version+".tmp")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := i.download(tmpDir); err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, installJarName)); err != nil {
+		return "", "", fmt.Errorf("archive doesn't contain %s", installJarName)
+	}
+
+	// the stale copy might still be in use, in which case it can't be removed
+	// on some platforms and is used once more
+	if stale {
+		_ = os.RemoveAll(dir)
+	}
+	// another process might have installed the same release in the meantime,
+	// in which case its copy is used
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, statErr := os.Stat(jarPath); statErr != nil {
+			return "", "", err
+		}
+	}
+	return jarPath, libPath, nil
+}
+
+func (i *installer) archiveURL() string {
+	return fmt.Sprintf("%s/dynamodb_local_%s.tar.gz", strings.TrimRight(i.baseURL, "/"), i.version)
+}
+
+// download fetches the release archive, verifies its checksum and unpacks it
+// into dir. A checksum fetched from the same server as the archive only
+// protects against corrupted downloads, not against a compromised server.
+func (i *installer) download(dir string) error {
+	checksum := i.checksum
+	if checksum == "" {
+		var err error
+		checksum, err = i.fetchChecksum()
+		if err != nil {
+			return err
+		}
+	}
+
+	body, err := i.get(i.archiveURL())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	archive, err := ioutil.TempFile(dir, "archive")
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, hash), body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", i.archiveURL(), err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, checksum) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", i.archiveURL(), checksum, sum)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := untar(archive, dir); err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", i.archiveURL(), err)
+	}
+	archive.Close()
+	return os.Remove(archive.Name())
+}
+
+// fetchChecksum fetches the SHA-256 checksum published alongside the release
+// archive.
+func (i *installer) fetchChecksum() (string, error) {
+	body, err := i.get(i.archiveURL() + ".sha256")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file for %s", i.archiveURL())
+	}
+	return fields[0], nil
+}
+
+func (i *installer) get(url string) (io.ReadCloser, error) {
+	resp, err := i.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// untar unpacks the gzipped tar archive read from r into dir.
+func untar(r io.Reader, dir string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// entries such as "./" resolve to dir itself
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0777|0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// InstallerOption is a unit of Installer configuration.
+type InstallerOption func(*installer)
+
+// CustomInstallBaseURL makes it possible to override the default location
+// from which the DynamoDB Local release archives are downloaded.
+func CustomInstallBaseURL(baseURL string) InstallerOption {
+	return func(i *installer) {
+		i.baseURL = baseURL
+	}
+}
+
+// CustomInstallVersion makes it possible to install a specific release of
+// DynamoDB Local (e.g. "2023-12-14") instead of the latest one.
+func CustomInstallVersion(version string) InstallerOption {
+	return func(i *installer) {
+		i.version = version
+	}
+}
+
+// CustomInstallChecksum makes it possible to provide the expected SHA-256
+// checksum of the release archive. By default the checksum published
+// alongside the archive is used, which only proves the integrity of the
+// download, as it comes from the same server. Pinning the checksum of a
+// specific version also protects against tampered releases.
+func CustomInstallChecksum(checksum string) InstallerOption {
+	return func(i *installer) {
+		i.checksum = checksum
+	}
+}
+
+// CustomInstallCacheDir makes it possible to override the default directory
+// (ddblocal in the user cache directory) into which releases are unpacked.
+func CustomInstallCacheDir(cacheDir string) InstallerOption {
+	return func(i *installer) {
+		i.cacheDir = cacheDir
+	}
+}
+
+// CustomInstallHTTPClient makes it possible to override the HTTP client used
+// to download the releases.
+func CustomInstallHTTPClient(client *http.Client) InstallerOption {
+	return func(i *installer) {
+		i.client = client
+	}
+}
+
+// NewInstaller returns a new instance of Installer. Releases are cached per
+// version, and the "latest" release is downloaded again once a week.
+func NewInstaller(options ...InstallerOption) Installer {
+	i := &installer{
+		client:  http.DefaultClient,
+		baseURL: defaultInstallBaseURL,
+		version: defaultInstallVersion,
+	}
+	for _, option := range options {
+		option(i)
+	}
+	return i
+}
@@ -0,0 +1,215 @@
+package ddblocal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/ddblocal"
+)
+
+func TestInstallsRelease(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{
+		"DynamoDBLocal.jar":                   "jar",
+		"DynamoDBLocal_lib/libsqlite4java.so": "lib",
+	})
+	ts := testReleaseServer(archive, sha256Hex(archive), nil)
+	defer ts.Close()
+
+	cacheDir := t.TempDir()
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallVersion("2023-12-14"),
+		ddblocal.CustomInstallCacheDir(cacheDir),
+	)
+
+	jarPath, libPath, err := inst.Install()
+	ok(t, err)
+
+	equals(t, filepath.Join(cacheDir, "2023-12-14", "DynamoDBLocal.jar"), jarPath)
+	equals(t, filepath.Join(cacheDir, "2023-12-14", "DynamoDBLocal_lib"), libPath)
+	jar, err := ioutil.ReadFile(jarPath)
+	ok(t, err)
+	equals(t, "jar", string(jar))
+	lib, err := ioutil.ReadFile(filepath.Join(libPath, "libsqlite4java.so"))
+	ok(t, err)
+	equals(t, "lib", string(lib))
+}
+
+func TestInstallReusesCachedRelease(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{"DynamoDBLocal.jar": "jar"})
+	var downloads int32
+	ts := testReleaseServer(archive, sha256Hex(archive), &downloads)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+	)
+
+	jarPath1, _, err := inst.Install()
+	ok(t, err)
+	jarPath2, _, err := inst.Install()
+	ok(t, err)
+
+	equals(t, jarPath1, jarPath2)
+	equals(t, int32(1), atomic.LoadInt32(&downloads))
+}
+
+func TestInstallRefreshesStaleLatestRelease(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{"DynamoDBLocal.jar": "jar"})
+	var downloads int32
+	ts := testReleaseServer(archive, sha256Hex(archive), &downloads)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+	)
+
+	jarPath, _, err := inst.Install()
+	ok(t, err)
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	ok(t, os.Chtimes(jarPath, old, old))
+
+	_, _, err = inst.Install()
+	ok(t, err)
+	equals(t, int32(2), atomic.LoadInt32(&downloads))
+
+	_, _, err = inst.Install()
+	ok(t, err)
+	equals(t, int32(2), atomic.LoadInt32(&downloads))
+}
+
+func TestInstallAcceptsCurrentDirectoryEntries(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{
+		"./":                  "",
+		"./DynamoDBLocal.jar": "jar",
+	})
+	ts := testReleaseServer(archive, sha256Hex(archive), nil)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+	)
+
+	jarPath, _, err := inst.Install()
+	ok(t, err)
+	jar, err := ioutil.ReadFile(jarPath)
+	ok(t, err)
+	equals(t, "jar", string(jar))
+}
+
+func TestInstallFailsOnChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{"DynamoDBLocal.jar": "jar"})
+	ts := testReleaseServer(archive, sha256Hex([]byte("something else")), nil)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+	)
+
+	_, _, err := inst.Install()
+	assert(t, err != nil, "expected an error")
+	assert(t, strings.Contains(err.Error(), "checksum mismatch"), "unexpected error: %v", err)
+}
+
+func TestInstallUsesProvidedChecksum(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{"DynamoDBLocal.jar": "jar"})
+	ts := testReleaseServer(archive, sha256Hex(archive), nil)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+		ddblocal.CustomInstallChecksum(sha256Hex([]byte("something else"))),
+	)
+
+	_, _, err := inst.Install()
+	assert(t, err != nil, "expected an error")
+	assert(t, strings.Contains(err.Error(), "checksum mismatch"), "unexpected error: %v", err)
+}
+
+func TestInstallRejectsPathsOutsideCacheDir(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, map[string]string{"../DynamoDBLocal.jar": "jar"})
+	ts := testReleaseServer(archive, sha256Hex(archive), nil)
+	defer ts.Close()
+
+	inst := ddblocal.NewInstaller(
+		ddblocal.CustomInstallBaseURL(ts.URL),
+		ddblocal.CustomInstallCacheDir(t.TempDir()),
+	)
+
+	_, _, err := inst.Install()
+	assert(t, err != nil, "expected an error")
+	assert(t, strings.Contains(err.Error(), "invalid path in archive"), "unexpected error: %v", err)
+}
+
+// testReleaseServer serves the archive and its checksum the way they are
+// published for DynamoDB Local releases, counting downloads of the archive.
+func testReleaseServer(archive []byte, checksum string, downloads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".tar.gz.sha256"):
+			fmt.Fprintf(w, "%s  %s\n", checksum, strings.TrimSuffix(filepath.Base(r.URL.Path), ".sha256"))
+		case strings.HasSuffix(r.URL.Path, ".tar.gz"):
+			if downloads != nil {
+				atomic.AddInt32(downloads, 1)
+			}
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		// names ending with a slash are directories
+		if strings.HasSuffix(name, "/") {
+			ok(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}))
+			continue
+		}
+		ok(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		ok(t, err)
+	}
+	ok(t, tw.Close())
+	ok(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
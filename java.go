@@ -0,0 +1,114 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrJavaNotFound is returned when no Java runtime can be found.
+	ErrJavaNotFound = errors.New("java runtime not found")
+	// ErrJarNotFound is returned when the DynamoDB Local jar doesn't exist.
+	ErrJarNotFound = errors.New("DynamoDB Local jar not found")
+	// ErrLibNotFound is returned when the DynamoDB Local native library
+	// directory doesn't exist.
+	ErrLibNotFound = errors.New("DynamoDB Local library directory not found")
+)
+
+// defaultMinJavaVersion is the minimum Java version required by DynamoDB
+// Local 2.x releases.
+const defaultMinJavaVersion = 11
+
+// JavaVersionError is returned when the Java runtime is too old for the
+// DynamoDB Local release.
+type JavaVersionError struct {
+	Path     string
+	Version  int
+	Required int
+}
+
+func (e *JavaVersionError) Error() string {
+	return fmt.Sprintf("java runtime %s has version %d, but at least version %d is required", e.Path, e.Version, e.Required)
+}
+
+var javaVersionRegexp = regexp.MustCompile(`version "([0-9]+)(?:\.([0-9]+))?`)
+
+// findJava returns the path of the Java runtime, which is either the
+// explicitly configured one, the one in JAVA_HOME or the one in PATH.
+func findJava(javaPath string) (string, error) {
+	if javaPath != "" {
+		if _, err := os.Stat(javaPath); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrJavaNotFound, javaPath)
+		}
+		return javaPath, nil
+	}
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		name := "java"
+		if runtime.GOOS == "windows" {
+			name = "java.exe"
+		}
+		javaPath := filepath.Join(javaHome, "bin", name)
+		if _, err := os.Stat(javaPath); err != nil {
+			return "", fmt.Errorf("%w: %s (from JAVA_HOME)", ErrJavaNotFound, javaPath)
+		}
+		return javaPath, nil
+	}
+	javaPath, err := exec.LookPath("java")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrJavaNotFound, err)
+	}
+	return javaPath, nil
+}
+
+// javaVersion returns the major version of the Java runtime at javaPath.
+func javaVersion(ctx context.Context, javaPath string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, javaPath, "-version").CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine the version of %s: %w", javaPath, err)
+	}
+	m := javaVersionRegexp.FindSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("failed to determine the version of %s from %q", javaPath, out)
+	}
+	major, _ := strconv.Atoi(string(m[1]))
+	// versions up to Java 8 are reported as 1.x
+	if major == 1 && len(m[2]) > 0 {
+		major, _ = strconv.Atoi(string(m[2]))
+	}
+	return major, nil
+}
+
+// checkJava verifies that the Java runtime is present and recent enough and
+// that the DynamoDB Local distribution is in place, and returns the path of
+// the Java runtime.
+func (e *Emulator) checkJava(ctx context.Context) (string, error) {
+	javaPath, err := findJava(e.javaPath)
+	if err != nil {
+		return "", err
+	}
+	version, err := javaVersion(ctx, javaPath)
+	if err != nil {
+		return "", err
+	}
+	if version < e.minJavaVersion {
+		return "", &JavaVersionError{Path: javaPath, Version: version, Required: e.minJavaVersion}
+	}
+	if info, err := os.Stat(e.jarPath); e.jarPath == "" || err != nil || info.IsDir() {
+		return "", fmt.Errorf("%w: %q", ErrJarNotFound, e.jarPath)
+	}
+	if info, err := os.Stat(e.libPath); e.libPath == "" || err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: %q", ErrLibNotFound, e.libPath)
+	}
+	return javaPath, nil
+}
@@ -0,0 +1,136 @@
+package ddblocal_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+func TestInitFailsWhenJavaMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		ddblocal.CustomJavaPath(filepath.Join(t.TempDir(), "java")),
+		fakeDistribution(t),
+	)
+	assert(t, errors.Is(err, ddblocal.ErrJavaNotFound), "expected ErrJavaNotFound, got %v", err)
+}
+
+func TestInitFailsWhenJavaTooOld(t *testing.T) {
+	t.Parallel()
+
+	home := fakeJavaHome(t, `java version "1.8.0_292"`, "exit 1")
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		ddblocal.CustomJavaPath(filepath.Join(home, "bin", "java")),
+		fakeDistribution(t),
+	)
+	var versionErr *ddblocal.JavaVersionError
+	assert(t, errors.As(err, &versionErr), "expected JavaVersionError, got %v", err)
+	equals(t, 8, versionErr.Version)
+	equals(t, 11, versionErr.Required)
+}
+
+func TestInitAcceptsCustomMinJavaVersion(t *testing.T) {
+	t.Parallel()
+
+	home := fakeJavaHome(t, `java version "1.8.0_292"`, "echo ready; exec sleep 10")
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomJavaPath(filepath.Join(home, "bin", "java")),
+		ddblocal.CustomMinJavaVersion(8),
+		fakeDistribution(t),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+}
+
+func TestInitUsesJavaHome(t *testing.T) {
+	home := fakeJavaHome(t, `openjdk version "21" 2023-09-19`, "echo java from JAVA_HOME; exec sleep 10")
+	setenv(t, "JAVA_HOME", home)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		fakeDistribution(t),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+
+	equals(t, "java from JAVA_HOME\n", ddb.Logs())
+}
+
+func TestInitFailsWhenJavaHomeInvalid(t *testing.T) {
+	setenv(t, "JAVA_HOME", t.TempDir())
+
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		fakeDistribution(t),
+	)
+	assert(t, errors.Is(err, ddblocal.ErrJavaNotFound), "expected ErrJavaNotFound, got %v", err)
+}
+
+func TestInitUsesJavaFromPath(t *testing.T) {
+	setenv(t, "JAVA_HOME", "")
+	fakeExecutable(t, "java", `if [ "$1" = "-version" ]; then echo 'openjdk version "11.0.2"' >&2; exit 0; fi; echo ready; exec sleep 10`)
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		fakeDistribution(t),
+	)
+	ok(t, err)
+	ok(t, ddb.Close())
+}
+
+func TestInitFailsWhenJarMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		fakeJava(t, "exit 1"),
+		ddblocal.CustomJarPath(filepath.Join(t.TempDir(), "DynamoDBLocal.jar")),
+	)
+	assert(t, errors.Is(err, ddblocal.ErrJarNotFound), "expected ErrJarNotFound, got %v", err)
+}
+
+func TestInitFailsWhenLibMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		fakeJava(t, "exit 1"),
+		ddblocal.CustomLibPath(""),
+	)
+	assert(t, errors.Is(err, ddblocal.ErrLibNotFound), "expected ErrLibNotFound, got %v", err)
+}
+
+// neverPresentChecker returns a PresenceChecker mock which always reports the
+// emulator as absent.
+func neverPresentChecker() *mocks.PresenceCheckerMock {
+	return &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool { return false },
+	}
+}
+
+// setenv sets the environment variable for the duration of the test.
+func setenv(t *testing.T, key, value string) {
+	prev, isSet := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if isSet {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package ddblocal
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// keepFlagName is the name of the test flag registered by Main which enables
+// KeepFailedTables for a single run.
+const keepFlagName = "ddblocal.keep"
+
+// maxDumpItems bounds the number of items dumped for a kept table, so that a
+// large table doesn't flood the test log.
+const maxDumpItems = 100
+
+// KeepFailedTables makes the Runner keep the tables of failed tests instead of
+// deleting them, and log their names together with a dump of their schema and
+// items. The same can be achieved for a single run of tests using Main with
+// the -ddblocal.keep test flag. The tables are only kept for as long as the DynamoDB Local
+// instance keeps its data, which unless CustomDBPath is used is until it's
+// terminated.
+func KeepFailedTables() EmulatorOption {
+	return func(e *Emulator) {
+		e.keepFailedTables = true
+	}
+}
+
+// keepTable logs the name of the kept table and dumps its contents.
+func (e *Emulator) keepTable(ctx context.Context, t testing.TB, tableName string) {
+	dump, err := e.dumpTable(ctx, tableName)
+	if err != nil {
+		t.Errorf("kept table %s of the failed test, but failed to dump it: %v", tableName, err)
+		return
+	}
+	t.Logf("kept table %s of the failed test\n%s", tableName, dump)
+}
+
+// registerKeepFlag registers the -ddblocal.keep flag on the command line flag
+// set unless it's already registered, and returns a function reporting whether
+// it was set.
+func registerKeepFlag() func() bool {
+	if flag.Lookup(keepFlagName) == nil {
+		flag.Bool(keepFlagName, false, "keep the tables of failed tests and dump their contents to the test log")
+	}
+	f := flag.Lookup(keepFlagName)
+	return func() bool {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return false
+		}
+		keep, _ := getter.Get().(bool)
+		return keep
+	}
+}
+
+// dumpTable returns a readable description of the schema of the table followed
+// by up to maxDumpItems of its items, one JSON object per line.
+func (e *Emulator) dumpTable(ctx context.Context, tableName string) (string, error) {
+	out, err := e.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema: %s\n", tableSchema(out.Table))
+
+	var items []string
+	truncated := false
+	in := &dynamodb.ScanInput{TableName: aws.String(tableName)}
+	for !truncated {
+		page, err := e.client.ScanWithContext(ctx, in)
+		if err != nil {
+			return "", err
+		}
+		for _, item := range page.Items {
+			if len(items) == maxDumpItems {
+				truncated = true
+				break
+			}
+			var v interface{}
+			if err := dynamodbattribute.UnmarshalMap(item, &v); err != nil {
+				return "", err
+			}
+			line, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, string(line))
+		}
+		if len(page.LastEvaluatedKey) == 0 {
+			break
+		}
+		in.ExclusiveStartKey = page.LastEvaluatedKey
+	}
+	if truncated {
+		fmt.Fprintf(&b, "items (first %d):", len(items))
+	} else {
+		fmt.Fprintf(&b, "items (%d):", len(items))
+	}
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n%s", item)
+	}
+	if truncated {
+		b.WriteString("\n... more items omitted")
+	}
+	return b.String(), nil
+}
+
+// tableSchema returns the parts of the table description which describe its
+// structure rather than its state.
+func tableSchema(table *dynamodb.TableDescription) *dynamodb.TableDescription {
+	if table == nil {
+		return nil
+	}
+	schema := &dynamodb.TableDescription{
+		AttributeDefinitions:  table.AttributeDefinitions,
+		KeySchema:             table.KeySchema,
+		LocalSecondaryIndexes: table.LocalSecondaryIndexes,
+		StreamSpecification:   table.StreamSpecification,
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		schema.GlobalSecondaryIndexes = append(schema.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  gsi.IndexName,
+			KeySchema:  gsi.KeySchema,
+			Projection: gsi.Projection,
+		})
+	}
+	return schema
+}
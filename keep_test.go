@@ -0,0 +1,180 @@
+package ddblocal_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+// cleanupRecorder is a testing.TB which records the cleanup functions and the
+// messages passed to Logf and Errorf instead of passing them to the test, and
+// reports the test as failed if failed is set.
+type cleanupRecorder struct {
+	testing.TB
+	failed   bool
+	cleanups []func()
+	logs     []string
+	errs     []string
+}
+
+func (r *cleanupRecorder) Failed() bool {
+	return r.failed
+}
+
+func (r *cleanupRecorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *cleanupRecorder) Logf(format string, args ...interface{}) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+func (r *cleanupRecorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+// cleanup runs the recorded cleanup functions in the reverse order.
+func (r *cleanupRecorder) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func keepMocks(deleteErr error) *mocks.DynamoDBAPIMock {
+	return &mocks.DynamoDBAPIMock{
+		CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+			return activeTable(in1), nil
+		},
+		DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+			return nil, deleteErr
+		},
+		DescribeTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableName:   in1.TableName,
+				TableStatus: aws.String(dynamodb.TableStatusActive),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				},
+			}}, nil
+		},
+		ScanWithContextFunc: func(_ context.Context, in1 *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+			if in1.ExclusiveStartKey == nil {
+				return &dynamodb.ScanOutput{
+					Items: []map[string]*dynamodb.AttributeValue{
+						{"id": {S: aws.String("1")}, "count": {N: aws.String("2")}},
+					},
+					LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("1")}},
+				}, nil
+			}
+			return &dynamodb.ScanOutput{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{"id": {S: aws.String("2")}},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestRunnerKeepsTablesOfFailedTests(t *testing.T) {
+	t.Parallel()
+
+	ddbcm := keepMocks(nil)
+	ddb := newRunnerEmulator(t, ddbcm, ddblocal.KeepFailedTables())
+
+	r := &cleanupRecorder{TB: t, failed: true}
+	ddb.Runner(r, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {})
+	r.cleanup()
+
+	equals(t, 0, len(ddbcm.DeleteTableWithContextCalls()))
+	equals(t, []string(nil), r.errs)
+	equals(t, 1, len(r.logs))
+	lines := strings.Split(r.logs[0], "\n")
+	equals(t, "kept table test_name of the failed test", lines[0])
+	assert(t, strings.Contains(r.logs[0], `AttributeName: "id"`), "expected the schema in the dump:\n%s", r.logs[0])
+	equals(t, []string{
+		"items (2):",
+		`{"count":2,"id":"1"}`,
+		`{"id":"2"}`,
+	}, lines[len(lines)-3:])
+}
+
+func TestRunnerLimitsDumpOfKeptTables(t *testing.T) {
+	t.Parallel()
+
+	ddbcm := keepMocks(nil)
+	ddbcm.ScanWithContextFunc = func(_ context.Context, in1 *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+		page := &dynamodb.ScanOutput{
+			LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("next")}},
+		}
+		for i := 0; i < 60; i++ {
+			page.Items = append(page.Items, map[string]*dynamodb.AttributeValue{"id": {N: aws.String(fmt.Sprint(i))}})
+		}
+		return page, nil
+	}
+	ddb := newRunnerEmulator(t, ddbcm, ddblocal.KeepFailedTables())
+
+	r := &cleanupRecorder{TB: t, failed: true}
+	ddb.Runner(r, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {})
+	r.cleanup()
+
+	equals(t, 2, len(ddbcm.ScanWithContextCalls()))
+	equals(t, 1, len(r.logs))
+	lines := strings.Split(r.logs[0], "\n")
+	equals(t, "items (first 100):", lines[len(lines)-102])
+	equals(t, `{"id":39}`, lines[len(lines)-2])
+	equals(t, "... more items omitted", lines[len(lines)-1])
+}
+
+func TestRunnerDeletesTablesOfPassedTests(t *testing.T) {
+	t.Parallel()
+
+	ddbcm := keepMocks(nil)
+	ddbcm.DescribeTableWithContextFunc = tableNotFound
+	ddb := newRunnerEmulator(t, ddbcm, ddblocal.KeepFailedTables())
+
+	r := &cleanupRecorder{TB: t}
+	ddb.Runner(r, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {})
+	r.cleanup()
+
+	equals(t, 1, len(ddbcm.DeleteTableWithContextCalls()))
+	equals(t, 0, len(ddbcm.ScanWithContextCalls()))
+	equals(t, []string(nil), r.logs)
+	equals(t, []string(nil), r.errs)
+}
+
+func TestRunnerDeletesTablesOfFailedTestsByDefault(t *testing.T) {
+	t.Parallel()
+
+	ddbcm := keepMocks(nil)
+	ddbcm.DescribeTableWithContextFunc = tableNotFound
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	r := &cleanupRecorder{TB: t, failed: true}
+	ddb.Runner(r, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {})
+	r.cleanup()
+
+	equals(t, 1, len(ddbcm.DeleteTableWithContextCalls()))
+	equals(t, []string(nil), r.errs)
+}
+
+func TestRunnerReportsCleanupFailuresAsErrors(t *testing.T) {
+	t.Parallel()
+
+	ddbcm := keepMocks(errors.New("test error"))
+	ddb := newRunnerEmulator(t, ddbcm)
+
+	r := &cleanupRecorder{TB: t, failed: true}
+	ddb.Runner(r, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {})
+	r.cleanup()
+
+	equals(t, []string{"failed to delete table test_name: test error"}, r.errs)
+}
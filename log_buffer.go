@@ -0,0 +1,49 @@
+package ddblocal
+
+import (
+	"strings"
+	"sync"
+)
+
+// logBuffer is an io.Writer which retains only the most recent size bytes
+// written to it.
+type logBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if n >= b.size {
+		p = p[n-b.size:]
+		b.buf = b.buf[:0]
+	} else if overflow := len(b.buf) + n - b.size; overflow > 0 {
+		b.buf = b.buf[:copy(b.buf, b.buf[overflow:])]
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+// String returns the retained contents of the buffer.
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// Tail returns at most the last n lines of the retained contents of the
+// buffer.
+func (b *logBuffer) Tail(n int) string {
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{size: size}
+}
@@ -0,0 +1,70 @@
+package ddblocal
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+var (
+	defaultMu       sync.Mutex
+	defaultEmulator *Emulator
+)
+
+// Main starts an Emulator configured with the given options, runs the tests,
+// closes the Emulator and exits with the right code. It is meant to be called
+// from TestMain, while the tests access the Emulator through Default:
+//
+//	func TestMain(m *testing.M) {
+//		ddblocal.Main(m)
+//	}
+//
+// Main registers the -ddblocal.keep test flag, which enables KeepFailedTables
+// for a single run.
+func Main(m *testing.M, options ...EmulatorOption) {
+	os.Exit(runMain(m, os.Stderr, options...))
+}
+
+// runMain does the work of Main and returns the exit code.
+func runMain(m interface{ Run() int }, stderr io.Writer, options ...EmulatorOption) int {
+	keep := registerKeepFlag()
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if keep() {
+		options = append(options[:len(options):len(options)], KeepFailedTables())
+	}
+
+	e, err := New(options...)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to start the DynamoDB local emulator: %v\n", err)
+		return 1
+	}
+	setDefault(e)
+	defer setDefault(nil)
+
+	exitCode := m.Run()
+	if err := e.Close(); err != nil {
+		fmt.Fprintf(stderr, "failed to close the DynamoDB local emulator: %v\n", err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// Default returns the Emulator started by Main, or nil if Main is not used.
+func Default() *Emulator {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultEmulator
+}
+
+func setDefault(e *Emulator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultEmulator = e
+}
@@ -0,0 +1,80 @@
+package ddblocal_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/ddblocal"
+)
+
+// mainHelper runs the tests through ddblocal.Main when the test binary is run
+// as a helper process by TestMainRunsTests.
+func mainHelper(m *testing.M, mode string) {
+	endpoint := "http://localhost:1"
+	if mode == "present" {
+		ts := httptest.NewServer(dynamoDBHandler(nil))
+		endpoint = ts.URL
+	}
+	ddblocal.Main(m, ddblocal.CustomEndpoint(endpoint), ddblocal.CustomStartupTimeout(100*time.Millisecond))
+}
+
+func TestMainHelper(t *testing.T) {
+	if os.Getenv("DDBLOCAL_TEST_MAIN") == "" {
+		t.SkipNow()
+	}
+	ddb := ddblocal.Default()
+	assert(t, ddb != nil, "expected the default emulator to be set")
+	t.Logf("endpoint: %s", ddb.Endpoint())
+	t.Logf("keep failed tables: %t", *ddb.Config().KeepFailedTables)
+}
+
+func runMainHelper(t *testing.T, mode string, arg ...string) (string, int) {
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestMainHelper$", "-test.v"}, arg...)...)
+	cmd.Env = append(os.Environ(), "DDBLOCAL_TEST_MAIN="+mode)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out.String(), exitErr.ExitCode()
+	}
+	ok(t, err)
+	return out.String(), 0
+}
+
+func TestMainRunsTests(t *testing.T) {
+	t.Parallel()
+
+	out, code := runMainHelper(t, "present")
+	equals(t, 0, code)
+	assert(t, strings.Contains(out, "--- PASS: TestMainHelper"), "unexpected output:\n%s", out)
+	assert(t, strings.Contains(out, "endpoint: http://127.0.0.1:"), "unexpected output:\n%s", out)
+}
+
+func TestMainRegistersKeepFlag(t *testing.T) {
+	t.Parallel()
+
+	out, code := runMainHelper(t, "present")
+	equals(t, 0, code)
+	assert(t, strings.Contains(out, "keep failed tables: false"), "unexpected output:\n%s", out)
+
+	out, code = runMainHelper(t, "present", "-ddblocal.keep")
+	equals(t, 0, code)
+	assert(t, strings.Contains(out, "keep failed tables: true"), "unexpected output:\n%s", out)
+}
+
+func TestMainFailsWhenEmulatorFails(t *testing.T) {
+	t.Parallel()
+
+	out, code := runMainHelper(t, "absent")
+	equals(t, 1, code)
+	assert(t, strings.Contains(out, "failed to start the DynamoDB local emulator: no DynamoDB compatible endpoint is running at http://localhost:1"), "unexpected output:\n%s", out)
+	assert(t, !strings.Contains(out, "TestMainHelper"), "expected no tests to run:\n%s", out)
+}
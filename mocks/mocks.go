@@ -2,5 +2,11 @@ package mocks
 
 //go:generate moq -out string_generator.go -pkg mocks .. StringGenerator
 //go:generate moq -out presence_checker.go -pkg mocks .. PresenceChecker
+//go:generate moq -out presence_checker_context.go -pkg mocks .. PresenceCheckerContext
+//go:generate moq -out prober.go -pkg mocks .. Prober
 //go:generate moq -out executor_terminator.go -pkg mocks .. ExecutorTerminator
+//go:generate moq -out executor_terminator_context.go -pkg mocks .. ExecutorTerminatorContext
 //go:generate moq -out client_initializer.go -pkg mocks .. ClientInitializer
+//go:generate moq -out client_initializer_context.go -pkg mocks .. ClientInitializerContext
+//go:generate moq -out process_monitor.go -pkg mocks .. ProcessMonitor
+//go:generate moq -out installer.go -pkg mocks .. Installer
@@ -0,0 +1,64 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrExited is returned when the DynamoDB Local process started by the
+// Emulator exits unexpectedly.
+var ErrExited = errors.New("DynamoDB Local exited unexpectedly")
+
+// monitor watches the DynamoDB Local process started by the Emulator, if the
+// ExecutorTerminator implements ProcessMonitor, and either restarts it when it
+// exits unexpectedly or records the reason of the exit. Every call starts a
+// new generation of monitoring, so that exits of processes stopped on purpose
+// are ignored. It must be called with e.mu held once the Emulator is in use.
+func (e *Emulator) monitor() {
+	pm, isMonitor := e.executor().(ProcessMonitor)
+	if !isMonitor || !e.owned {
+		return
+	}
+	e.generation++
+	go e.watch(pm, pm.Done(), e.generation)
+}
+
+func (e *Emulator) watch(pm ProcessMonitor, done <-chan struct{}, generation int) {
+	<-done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closing || generation != e.generation {
+		return
+	}
+
+	exitErr := ErrExited
+	if err := pm.Err(); err != nil {
+		exitErr = fmt.Errorf("%w: %v", ErrExited, err)
+	}
+	for e.restarts > 0 {
+		e.restarts--
+		err := e.relaunch()
+		if err == nil {
+			return
+		}
+		exitErr = fmt.Errorf("%w (restart failed: %v)", exitErr, err)
+	}
+	e.exitErr = e.withLogs(exitErr)
+}
+
+// relaunch runs the DynamoDB Local process again after it exited or was
+// stopped and resumes monitoring it. It must be called with e.mu held.
+func (e *Emulator) relaunch() error {
+	if err := e.run(context.Background()); err != nil {
+		return err
+	}
+	if pr, ok := e.executor().(pidReporter); ok && e.shared != nil {
+		if err := e.shared.recordPid(pr.Pid(), e.args()); err != nil {
+			return err
+		}
+	}
+	e.monitor()
+	return nil
+}
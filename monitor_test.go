@@ -0,0 +1,150 @@
+package ddblocal_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+func TestRunnerFailsFastAfterUnexpectedExit(t *testing.T) {
+	t.Parallel()
+
+	proc := newFakeProcess()
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(proc.executorTerminator()),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomClientInitializer(&mocks.ClientInitializerMock{
+			InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+				return &mocks.DynamoDBAPIMock{}, nil
+			},
+		}),
+	)
+	ok(t, err)
+
+	proc.exit(errors.New("signal: killed"))
+	waitFor(t, func() bool { return ddb.Err() != nil })
+
+	err = ddb.Err()
+	assert(t, errors.Is(err, ddblocal.ErrExited), "expected ErrExited, got %v", err)
+	equals(t, "DynamoDB Local exited unexpectedly: signal: killed", err.Error())
+
+	msg := recordFatal(t, func(tb testing.TB) {
+		ddb.Runner(tb, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {
+			t.Error("test body should not be called")
+		})
+	})
+	equals(t, "DynamoDB Local exited unexpectedly: signal: killed", msg)
+}
+
+func TestRestartsAfterUnexpectedExit(t *testing.T) {
+	t.Parallel()
+
+	proc := newFakeProcess()
+	et := proc.executorTerminator()
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(et),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomRestarts(1),
+	)
+	ok(t, err)
+
+	proc.exit(errors.New("signal: killed"))
+	waitFor(t, func() bool { return len(et.ExecuteCalls()) == 2 })
+	ok(t, ddb.Err())
+
+	proc.exit(errors.New("exit status 1"))
+	waitFor(t, func() bool { return ddb.Err() != nil })
+	assert(t, strings.HasPrefix(ddb.Err().Error(), "DynamoDB Local exited unexpectedly: exit status 1"), "unexpected error: %v", ddb.Err())
+	equals(t, 2, len(et.ExecuteCalls()))
+}
+
+func TestCloseIsNotReportedAsUnexpectedExit(t *testing.T) {
+	t.Parallel()
+
+	proc := newFakeProcess()
+	et := proc.executorTerminator()
+	et.TerminateFunc = func() error {
+		proc.exit(nil)
+		return nil
+	}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(et),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomRestarts(1),
+	)
+	ok(t, err)
+
+	ok(t, ddb.Close())
+	time.Sleep(10 * time.Millisecond)
+	ok(t, ddb.Err())
+	equals(t, 1, len(et.ExecuteCalls()))
+}
+
+// fakeProcess simulates the lifecycle of processes run by an
+// ExecutorTerminator which implements ProcessMonitor.
+type fakeProcess struct {
+	mu   sync.Mutex
+	done chan struct{}
+	err  error
+}
+
+func newFakeProcess() *fakeProcess {
+	return &fakeProcess{}
+}
+
+func (p *fakeProcess) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = make(chan struct{})
+	p.err = nil
+}
+
+func (p *fakeProcess) exit(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+	close(p.done)
+}
+
+func (p *fakeProcess) executorTerminator() *monitoredExecutorTerminator {
+	return &monitoredExecutorTerminator{
+		ExecutorTerminatorMock: &mocks.ExecutorTerminatorMock{
+			ExecuteFunc: func(name string, arg ...string) error {
+				p.start()
+				return nil
+			},
+			TerminateFunc: func() error { return nil },
+		},
+		ProcessMonitorMock: &mocks.ProcessMonitorMock{
+			DoneFunc: func() <-chan struct{} {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return p.done
+			},
+			ErrFunc: func() error {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return p.err
+			},
+		},
+	}
+}
+
+// waitFor fails the test if the condition doesn't become true within a second.
+func waitFor(tb testing.TB, condition func() bool) {
+	tb.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			tb.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
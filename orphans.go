@@ -0,0 +1,99 @@
+package ddblocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pidRecord describes a DynamoDB Local process launched by an Emulator.
+type pidRecord struct {
+	Pid    int    `json:"pid"`
+	Parent int    `json:"parent"`
+	Args   string `json:"args"`
+}
+
+// recordLaunch records the DynamoDB Local process launched by the Emulator
+// in the pid directory, so that it can be cleaned up by a later run if the
+// current process dies without closing the Emulator.
+func (e *Emulator) recordLaunch(pid int, args []string) error {
+	if err := os.MkdirAll(e.pidDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(pidRecord{
+		Pid:    pid,
+		Parent: os.Getpid(),
+		Args:   strings.Join(args, " "),
+	})
+	if err != nil {
+		return err
+	}
+	e.pidFile = filepath.Join(e.pidDir, fmt.Sprintf("%d.json", pid))
+	return ioutil.WriteFile(e.pidFile, data, 0644)
+}
+
+// removeLaunchRecord removes the record created by recordLaunch.
+func (e *Emulator) removeLaunchRecord() {
+	if e.pidFile != "" {
+		_ = os.Remove(e.pidFile)
+		e.pidFile = ""
+	}
+}
+
+// sweepOrphans terminates DynamoDB Local processes recorded in the pid
+// directory whose launching process no longer exists. A process is only
+// terminated if its command line still matches the recorded one, so that
+// processes which merely reuse the pid are left alone, as are instances which
+// weren't launched by an Emulator.
+func sweepOrphans(pidDir string, gracePeriod time.Duration) {
+	matches, err := filepath.Glob(filepath.Join(pidDir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		data, err := ioutil.ReadFile(m)
+		if err != nil {
+			continue
+		}
+		var rec pidRecord
+		if err := json.Unmarshal(data, &rec); err != nil || rec.Pid <= 0 {
+			_ = os.Remove(m)
+			continue
+		}
+		if processAlive(rec.Parent) {
+			continue
+		}
+		// keep the record to try again later if the process may still be
+		// running
+		if err := stopRecorded(rec, gracePeriod); err != nil {
+			continue
+		}
+		_ = os.Remove(m)
+	}
+}
+
+// stopRecorded stops the recorded process if it's still running with the
+// recorded command line, so that a process which merely reuses the pid is
+// left alone. An error is returned if the command line of a running process
+// couldn't be read or the process couldn't be stopped, i.e. unless the
+// recorded process is known to be gone.
+func stopRecorded(rec pidRecord, gracePeriod time.Duration) error {
+	if !processAlive(rec.Pid) {
+		return nil
+	}
+	cmdline, err := processCommandLine(rec.Pid)
+	if err != nil {
+		if !processAlive(rec.Pid) {
+			return nil
+		}
+		return fmt.Errorf("failed to read the command line of process %d: %w", rec.Pid, err)
+	}
+	if !strings.Contains(cmdline, rec.Args) {
+		return nil
+	}
+	return stopProcessGroup(rec.Pid, gracePeriod)
+}
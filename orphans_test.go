@@ -0,0 +1,151 @@
+//go:build !windows
+// +build !windows
+
+package ddblocal_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+func TestInitRecordsLaunchedProcess(t *testing.T) {
+	t.Parallel()
+
+	pidDir := t.TempDir()
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		fakeJava(t, "echo ready; exec sleep 10"),
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomPidDir(pidDir),
+	)
+	ok(t, err)
+
+	matches, err := filepath.Glob(filepath.Join(pidDir, "*.json"))
+	ok(t, err)
+	equals(t, 1, len(matches))
+	var rec struct {
+		Pid    int `json:"pid"`
+		Parent int `json:"parent"`
+	}
+	data, err := ioutil.ReadFile(matches[0])
+	ok(t, err)
+	ok(t, json.Unmarshal(data, &rec))
+	equals(t, os.Getpid(), rec.Parent)
+	equals(t, fmt.Sprintf("%d.json", rec.Pid), filepath.Base(matches[0]))
+
+	ok(t, ddb.Close())
+	matches, err = filepath.Glob(filepath.Join(pidDir, "*.json"))
+	ok(t, err)
+	equals(t, 0, len(matches))
+}
+
+func TestInitTerminatesOrphanedProcesses(t *testing.T) {
+	t.Parallel()
+
+	pidDir := t.TempDir()
+	const deadParent = 2147483646
+
+	orphan := startProcess(t, "-jar", "orphan.jar", "-port", "1234")
+	recordProcess(t, pidDir, orphan.Process.Pid, deadParent, "-jar orphan.jar -port 1234")
+
+	reused := startProcess(t, "-jar", "reused.jar", "-port", "1234")
+	recordProcess(t, pidDir, reused.Process.Pid, deadParent, "-jar orphan.jar -port 1234")
+
+	inUse := startProcess(t, "-jar", "in-use.jar", "-port", "1234")
+	recordProcess(t, pidDir, inUse.Process.Pid, os.Getpid(), "-jar in-use.jar -port 1234")
+
+	var w syncBuffer
+	ddb, err := ddblocal.New(
+		fakeJava(t, "echo ready; exec sleep 10"),
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomPidDir(pidDir),
+	)
+	ok(t, err)
+	defer ddb.Close()
+
+	assert(t, !running(orphan), "expected the orphaned process to be terminated")
+	assert(t, running(reused), "expected the process reusing a recorded pid to be left alone")
+	assert(t, running(inUse), "expected the process with a live parent to be left alone")
+
+	_, err = os.Stat(filepath.Join(pidDir, fmt.Sprintf("%d.json", orphan.Process.Pid)))
+	assert(t, os.IsNotExist(err), "expected the record of the orphaned process to be removed")
+}
+
+// startProcess starts a long running process in its own process group with
+// the given arguments in its command line, which is killed at the end of the
+// test.
+func startProcess(t *testing.T, arg ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", append([]string{"-c", "while true; do sleep 0.1; done", "sh"}, arg...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	ok(t, cmd.Start())
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+	})
+	return cmd
+}
+
+// recordProcess records a process the way the Emulator does for the
+// processes it launches.
+func recordProcess(t *testing.T, pidDir string, pid, parent int, args string) {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"pid":    pid,
+		"parent": parent,
+		"args":   args,
+	})
+	ok(t, err)
+	ok(t, ioutil.WriteFile(filepath.Join(pidDir, fmt.Sprintf("%d.json", pid)), data, 0644))
+}
+
+// running reports whether the process started by cmd is still running.
+func running(cmd *exec.Cmd) bool {
+	return syscall.Kill(cmd.Process.Pid, 0) == nil
+}
+
+func TestSharedInstanceTerminatesInstanceWithoutLeases(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ok(t, os.MkdirAll(filepath.Join(dir, "8000"), 0755))
+
+	stale := startProcess(t, "-jar", "stale.jar", "-port", "8000")
+	data, err := json.Marshal(map[string]interface{}{
+		"pid":    stale.Process.Pid,
+		"parent": 2147483646,
+		"args":   "-jar stale.jar -port 8000",
+	})
+	ok(t, err)
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "8000", "pid"), data, 0644))
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+	defer ddb.Close()
+
+	assert(t, !running(stale), "expected the instance without leases to be terminated")
+	equals(t, 1, len(etm.ExecuteCalls()))
+}
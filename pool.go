@@ -0,0 +1,190 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// EmulatorPool spreads tests over several instances of DynamoDB Local, so
+// that a single instance doesn't become the bottleneck of a large parallel
+// test suite.
+type EmulatorPool struct {
+	emulators []*Emulator
+
+	mu   sync.Mutex
+	load []int
+}
+
+// Emulators returns the emulators in the pool.
+func (p *EmulatorPool) Emulators() []*Emulator {
+	return p.emulators
+}
+
+// Runner works like Emulator.Runner, running the test against the emulator
+// currently used by the fewest tests.
+func (p *EmulatorPool) Runner(t testing.TB, tableDef *dynamodb.CreateTableInput, f func(client dynamodbiface.DynamoDBAPI, tableName string), options ...RunnerOption) {
+	i := p.acquire()
+	t.Cleanup(func() {
+		p.release(i)
+	})
+	p.emulators[i].Runner(t, tableDef, f, options...)
+}
+
+// MultiTableRunner works like Emulator.MultiTableRunner, running the test
+// against the emulator currently used by the fewest tests.
+func (p *EmulatorPool) MultiTableRunner(t testing.TB, tableDefs map[string]*dynamodb.CreateTableInput, f func(client dynamodbiface.DynamoDBAPI, tableNames map[string]string), options ...RunnerOption) {
+	i := p.acquire()
+	t.Cleanup(func() {
+		p.release(i)
+	})
+	p.emulators[i].MultiTableRunner(t, tableDefs, f, options...)
+}
+
+// acquire assigns a test to the least loaded emulator and returns its index.
+func (p *EmulatorPool) acquire() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	min := 0
+	for i, l := range p.load {
+		if l < p.load[min] {
+			min = i
+		}
+	}
+	p.load[min]++
+	return min
+}
+
+func (p *EmulatorPool) release(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.load[i]--
+}
+
+// Close closes every emulator in the pool and returns the first error
+// encountered.
+func (p *EmulatorPool) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext works like Close, passing ctx to Emulator.CloseContext.
+func (p *EmulatorPool) CloseContext(ctx context.Context) error {
+	return closeAll(ctx, p.emulators)
+}
+
+func closeAll(ctx context.Context, emulators []*Emulator) error {
+	var firstErr error
+	for _, e := range emulators {
+		if e == nil {
+			continue
+		}
+		if err := e.CloseContext(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewPool starts n instances of DynamoDB Local on distinct free ports and
+// returns a pool of emulators configured to use them. The options are applied
+// to every emulator, except that the port is always chosen by the pool. An
+// ExecutorTerminator, ClientInitializer or log writer can't be shared by the
+// emulators, so the options providing them are rejected in favour of
+// NewPoolFunc.
+func NewPool(n int, options ...EmulatorOption) (*EmulatorPool, error) {
+	return NewPoolWithContext(context.Background(), n, options...)
+}
+
+// NewPoolWithContext works like NewPool, but gives up on starting the
+// instances when ctx is done.
+func NewPoolWithContext(ctx context.Context, n int, options ...EmulatorOption) (*EmulatorPool, error) {
+	ddb := &Emulator{}
+	for _, option := range options {
+		option(ddb)
+	}
+	switch {
+	case ddb.et != nil:
+		return nil, errors.New("incompatible options: NewPool and CustomExecutorTerminator, use NewPoolFunc instead")
+	case ddb.ci != nil:
+		return nil, errors.New("incompatible options: NewPool and CustomClientInitializer, use NewPoolFunc instead")
+	case ddb.logWriter != nil:
+		return nil, errors.New("incompatible options: NewPool and CustomLogWriter, use NewPoolFunc instead")
+	}
+	return NewPoolFuncWithContext(ctx, n, func(int) []EmulatorOption {
+		return options
+	})
+}
+
+// NewPoolFunc works like NewPool, but the options of the i-th emulator are
+// returned by options(i), so that each of them can be given its own
+// ExecutorTerminator, ClientInitializer, log writer or database directory. The
+// emulators of a pool can't use a custom endpoint or share their instances
+// with other processes.
+func NewPoolFunc(n int, options func(i int) []EmulatorOption) (*EmulatorPool, error) {
+	return NewPoolFuncWithContext(context.Background(), n, options)
+}
+
+// NewPoolFuncWithContext works like NewPoolFunc, but gives up on starting the
+// instances when ctx is done.
+func NewPoolFuncWithContext(ctx context.Context, n int, options func(i int) []EmulatorOption) (*EmulatorPool, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid pool size: %d", n)
+	}
+	memberOptions := make([][]EmulatorOption, n)
+	dbPaths := make(map[string]bool, n)
+	for i := range memberOptions {
+		memberOptions[i] = options(i)
+		ddb, err := configure(memberOptions[i])
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case ddb.endpoint != "":
+			return nil, errors.New("incompatible options: NewPool and CustomEndpoint")
+		case ddb.shareDir != "":
+			return nil, errors.New("incompatible options: NewPool and Shared")
+		}
+		// the instances would overwrite each other's database files
+		if ddb.dbPath != "" {
+			dbPath, err := filepath.Abs(ddb.dbPath)
+			if err != nil {
+				return nil, err
+			}
+			if dbPaths[dbPath] {
+				return nil, fmt.Errorf("incompatible options: CustomDBPath %s is used by several instances of the pool, use NewPoolFunc to give each its own", ddb.dbPath)
+			}
+			dbPaths[dbPath] = true
+		}
+	}
+	ports, err := freePorts(n)
+	if err != nil {
+		return nil, err
+	}
+
+	emulators := make([]*Emulator, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range emulators {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opts := append(memberOptions[i][:len(memberOptions[i]):len(memberOptions[i])], CustomPort(ports[i]))
+			emulators[i], errs[i] = NewWithContext(ctx, opts...)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			_ = closeAll(context.Background(), emulators)
+			return nil, err
+		}
+	}
+	return &EmulatorPool{emulators: emulators, load: make([]int, n)}, nil
+}
@@ -0,0 +1,213 @@
+package ddblocal_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+// poolMocks returns mocks which keep track of the instances launched on each
+// port, so that they can be shared by the emulators in a pool.
+func poolMocks(execErr func(port int) error) (*mocks.ExecutorTerminatorMock, *mocks.PresenceCheckerMock) {
+	var mu sync.Mutex
+	launched := make(map[int]bool)
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc: func(name string, arg ...string) error {
+			var port int
+			for i, a := range arg {
+				if i > 0 && arg[i-1] == "-port" {
+					port, _ = strconv.Atoi(a)
+				}
+			}
+			if err := execErr(port); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			launched[port] = true
+			return nil
+		},
+		TerminateFunc: func() error { return nil },
+	}
+	pcm := &mocks.PresenceCheckerMock{
+		IsPresentFunc: func(port int) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return launched[port]
+		},
+	}
+	return etm, pcm
+}
+
+func TestPoolStartsInstancesOnDistinctPorts(t *testing.T) {
+	t.Parallel()
+
+	etm, pcm := poolMocks(func(int) error { return nil })
+
+	pool, err := ddblocal.NewPoolFunc(3, func(int) []ddblocal.EmulatorOption {
+		return []ddblocal.EmulatorOption{
+			ddblocal.CustomExecutorTerminator(etm),
+			ddblocal.CustomPresenceChecker(pcm),
+			ddblocal.CustomPort(8000),
+		}
+	})
+	ok(t, err)
+
+	ports := make(map[int]bool)
+	for _, e := range pool.Emulators() {
+		assert(t, e.Port() != 8000, "expected the pool to choose the port")
+		ports[e.Port()] = true
+	}
+	equals(t, 3, len(ports))
+	equals(t, 3, len(etm.ExecuteCalls()))
+
+	ok(t, pool.Close())
+	equals(t, 3, len(etm.TerminateCalls()))
+}
+
+func TestPoolFailsIfAnInstanceFails(t *testing.T) {
+	t.Parallel()
+
+	var once sync.Once
+	etm, pcm := poolMocks(func(int) error {
+		var err error
+		once.Do(func() { err = fmt.Errorf("test error") })
+		return err
+	})
+
+	_, err := ddblocal.NewPoolFunc(3, func(int) []ddblocal.EmulatorOption {
+		return []ddblocal.EmulatorOption{
+			ddblocal.CustomExecutorTerminator(etm),
+			ddblocal.CustomPresenceChecker(pcm),
+		}
+	})
+	equals(t, "test error", err.Error())
+	equals(t, 2, len(etm.TerminateCalls()))
+}
+
+func TestPoolRejectsOptionsWhichCantBeShared(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		option ddblocal.EmulatorOption
+		msg    string
+	}{
+		{
+			name:   "executor terminator",
+			option: ddblocal.CustomExecutorTerminator(&mocks.ExecutorTerminatorMock{}),
+			msg:    "incompatible options: NewPool and CustomExecutorTerminator, use NewPoolFunc instead",
+		},
+		{
+			name:   "client initializer",
+			option: ddblocal.CustomClientInitializer(&mocks.ClientInitializerMock{}),
+			msg:    "incompatible options: NewPool and CustomClientInitializer, use NewPoolFunc instead",
+		},
+		{
+			name:   "log writer",
+			option: ddblocal.CustomLogWriter(ioutil.Discard),
+			msg:    "incompatible options: NewPool and CustomLogWriter, use NewPoolFunc instead",
+		},
+		{
+			name:   "endpoint",
+			option: ddblocal.CustomEndpoint("http://dynamodb:8000"),
+			msg:    "incompatible options: NewPool and CustomEndpoint",
+		},
+		{
+			name:   "shared",
+			option: ddblocal.CustomShareDir(t.TempDir()),
+			msg:    "incompatible options: NewPool and Shared",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ddblocal.NewPool(2, tc.option)
+			assert(t, err != nil, "expected an error")
+			equals(t, tc.msg, err.Error())
+		})
+	}
+}
+
+func TestPoolRejectsSharedDBPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, err := ddblocal.NewPool(2, ddblocal.CustomDBPath(dir))
+	assert(t, err != nil, "expected an error")
+	equals(t, "incompatible options: CustomDBPath "+dir+" is used by several instances of the pool, use NewPoolFunc to give each its own", err.Error())
+
+	etm, pcm := poolMocks(func(int) error { return nil })
+	pool, err := ddblocal.NewPoolFunc(2, func(i int) []ddblocal.EmulatorOption {
+		return []ddblocal.EmulatorOption{
+			ddblocal.CustomExecutorTerminator(etm),
+			ddblocal.CustomPresenceChecker(pcm),
+			ddblocal.CustomDBPath(filepath.Join(dir, strconv.Itoa(i))),
+		}
+	})
+	ok(t, err)
+	ok(t, pool.Close())
+}
+
+func TestPoolRunnerAssignsLeastLoadedEmulator(t *testing.T) {
+	t.Parallel()
+
+	etm, pcm := poolMocks(func(int) error { return nil })
+
+	clients := make(map[dynamodbiface.DynamoDBAPI]int)
+	var mu sync.Mutex
+	cim := &mocks.ClientInitializerMock{
+		InitClientFunc: func(port int) (dynamodbiface.DynamoDBAPI, error) {
+			client := &mocks.DynamoDBAPIMock{
+				CreateTableWithContextFunc: func(_ context.Context, in1 *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+					return activeTable(in1), nil
+				},
+				DeleteTableWithContextFunc: func(_ context.Context, in1 *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+					return nil, nil
+				},
+				DescribeTableWithContextFunc: tableNotFound,
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			clients[client] = port
+			return client, nil
+		},
+	}
+
+	pool, err := ddblocal.NewPoolFunc(2, func(int) []ddblocal.EmulatorOption {
+		return []ddblocal.EmulatorOption{
+			ddblocal.CustomExecutorTerminator(etm),
+			ddblocal.CustomPresenceChecker(pcm),
+			ddblocal.CustomClientInitializer(cim),
+		}
+	})
+	ok(t, err)
+	defer pool.Close()
+
+	var used []int
+	run := func(t *testing.T) {
+		pool.Runner(t, &dynamodb.CreateTableInput{}, func(client dynamodbiface.DynamoDBAPI, _ string) {
+			used = append(used, clients[client])
+		})
+	}
+
+	t.Run("busy", func(t *testing.T) {
+		// the tables created by the Runner are kept until the subtest ends
+		run(t)
+		run(t)
+		run(t)
+	})
+	t.Run("idle", run)
+
+	ports := []int{pool.Emulators()[0].Port(), pool.Emulators()[1].Port()}
+	equals(t, []int{ports[0], ports[1], ports[0], ports[0]}, used)
+}
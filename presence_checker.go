@@ -3,20 +3,78 @@ package ddblocal
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 )
 
-type presenceChecker func(port int) bool
+// PortState describes what is listening on a port.
+type PortState int
+
+const (
+	// PortFree means nothing is listening on the port.
+	PortFree PortState = iota
+	// PortDynamoDB means a DynamoDB compatible endpoint is listening on the
+	// port.
+	PortDynamoDB
+	// PortForeign means a service which isn't DynamoDB compatible is
+	// listening on the port.
+	PortForeign
+)
+
+func (s PortState) String() string {
+	switch s {
+	case PortFree:
+		return "free"
+	case PortDynamoDB:
+		return "DynamoDB"
+	case PortForeign:
+		return "foreign"
+	}
+	return fmt.Sprintf("PortState(%d)", int(s))
+}
+
+// ProbeResult is the result of probing a port. Vendor and Version describe a
+// DynamoDB compatible endpoint, as far as they can be detected.
+type ProbeResult struct {
+	State   PortState
+	Vendor  string
+	Version string
+}
+
+// ErrPortInUse is returned by New when the port DynamoDB Local should run on
+// is occupied by a service which isn't DynamoDB compatible.
+var ErrPortInUse = errors.New("port is in use by a service which isn't DynamoDB compatible")
+
+const (
+	// probeTimeout bounds a single probe request.
+	probeTimeout = 500 * time.Millisecond
+
+	// probeAttempts is the number of requests which have to time out before
+	// the port is declared to be occupied by a foreign service.
+	probeAttempts = 3
+)
+
+type presenceChecker func(ctx context.Context, endpoint string) ProbeResult
 
 func (p presenceChecker) IsPresent(port int) bool {
-	return p(port)
+	return p.IsPresentContext(context.Background(), port)
+}
+
+func (p presenceChecker) IsPresentContext(ctx context.Context, port int) bool {
+	return p(ctx, localEndpoint(port)).State == PortDynamoDB
+}
+
+func (p presenceChecker) Probe(ctx context.Context, endpoint string) ProbeResult {
+	return p(ctx, endpoint)
 }
 
-// Checks if DynamoDB local instance is running by checking the sponse to a GET
-// request to the instance endpoint.
+// Probes the endpoint by checking the response to a GET request to it. The
+// port is free if the connection is refused and occupied by a foreign service
+// if the response is not the one of a DynamoDB endpoint.
 //
 // The "correct" response looks like this:
 //
@@ -27,39 +85,102 @@ func (p presenceChecker) IsPresent(port int) bool {
 //    "__type": "com.amazonaws.dynamodb.v20120810#MissingAuthenticationToken",
 //    "message": "Request must contain either a valid (registered) AWS access key ID or X.509 certificate."
 // }
-func isPresent(port int) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+func probe(ctx context.Context, endpoint string) ProbeResult {
+	for attempt := 1; ; attempt++ {
+		res, timedOut := probeOnce(ctx, endpoint)
+		if !timedOut || attempt == probeAttempts || ctx.Err() != nil {
+			return res
+		}
+	}
+}
+
+// probeOnce sends a single request to the endpoint. A request which times out
+// is reported separately, as a slow endpoint may still turn out to be a
+// DynamoDB endpoint, and is otherwise classified as a foreign service.
+func probeOnce(ctx context.Context, endpoint string) (res ProbeResult, timedOut bool) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://localhost:%d", port), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return false
+		return ProbeResult{State: PortFree}, false
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return false
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return ProbeResult{State: PortFree}, false
+		}
+		return ProbeResult{State: PortForeign}, isTimeout(err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 400 {
-		return false
+		return ProbeResult{State: PortForeign}, false
 	}
 
 	var respBody struct {
 		Type string `json:"__type"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return false
+		return ProbeResult{State: PortForeign}, isTimeout(err)
 	}
 	if !strings.HasPrefix(respBody.Type, "com.amazonaws.dynamodb") {
-		return false
+		return ProbeResult{State: PortForeign}, false
+	}
+
+	vendor, version := detectVendor(resp.Header)
+	return ProbeResult{State: PortDynamoDB, Vendor: vendor, Version: version}, false
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// detectVendor tells DynamoDB Local apart from other DynamoDB compatible
+// endpoints by the headers of their responses. The version is only known when
+// the endpoint advertises it in the Server header as "product/version".
+func detectVendor(header http.Header) (vendor string, version string) {
+	server := header.Get("Server")
+	if product := strings.SplitN(server, "/", 2); len(product) == 2 {
+		if fields := strings.Fields(product[1]); len(fields) > 0 {
+			version = fields[0]
+		}
 	}
 
-	return true
+	lower := strings.ToLower(server)
+	switch {
+	case strings.Contains(lower, "localstack"):
+		return "LocalStack", version
+	case strings.Contains(lower, "alternator") || strings.Contains(lower, "scylla"):
+		return "ScyllaDB Alternator", version
+	}
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-localstack") {
+			return "LocalStack", version
+		}
+	}
+	// DynamoDB Local runs on Jetty, whose version says nothing about the
+	// version of DynamoDB Local.
+	if strings.HasPrefix(lower, "jetty") {
+		version = ""
+	}
+	return "DynamoDB Local", version
 }
 
 // NewPresenceChecker returns a new instance of PresenceChecker with default
 // configuration.
 func NewPresenceChecker() PresenceChecker {
-	return presenceChecker(isPresent)
+	return newPresenceChecker()
+}
+
+// NewProber returns a new instance of Prober with default configuration.
+func NewProber() Prober {
+	return newPresenceChecker()
+}
+
+func newPresenceChecker() presenceChecker {
+	return presenceChecker(probe)
 }
@@ -1,12 +1,15 @@
 package ddblocal_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/fwojciec/ddblocal"
 )
@@ -89,6 +92,83 @@ func TestFailsPresenceTestIfServerDoesntRespond(t *testing.T) {
 	equals(t, false, res)
 }
 
+func dynamoDBHandler(header map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#MissingAuthenticationToken"}`))
+	})
+}
+
+func TestProbeReportsPortState(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		exp     ddblocal.ProbeResult
+	}{
+		{
+			name:    "DynamoDB Local",
+			handler: dynamoDBHandler(map[string]string{"Server": "Jetty(9.4.48.v20220622)"}),
+			exp:     ddblocal.ProbeResult{State: ddblocal.PortDynamoDB, Vendor: "DynamoDB Local"},
+		},
+		{
+			name:    "LocalStack",
+			handler: dynamoDBHandler(map[string]string{"Server": "LocalStack/3.0.2"}),
+			exp:     ddblocal.ProbeResult{State: ddblocal.PortDynamoDB, Vendor: "LocalStack", Version: "3.0.2"},
+		},
+		{
+			name: "foreign",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("<html></html>"))
+			}),
+			exp: ddblocal.ProbeResult{State: ddblocal.PortForeign},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(tc.handler)
+			defer ts.Close()
+
+			equals(t, tc.exp, ddblocal.NewProber().Probe(context.Background(), ts.URL))
+		})
+	}
+
+	t.Run("free", func(t *testing.T) {
+		t.Parallel()
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		equals(t, ddblocal.ProbeResult{State: ddblocal.PortFree}, ddblocal.NewProber().Probe(context.Background(), closed.URL))
+	})
+}
+
+func TestProbeRetriesSlowEndpoints(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	respond := dynamoDBHandler(nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+		}
+		respond.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	equals(t, ddblocal.PortDynamoDB, ddblocal.NewProber().Probe(context.Background(), ts.URL).State)
+	equals(t, int32(3), atomic.LoadInt32(&requests))
+}
+
 func testServerPort(addr string) (int, error) {
 	u, err := url.Parse(addr)
 	if err != nil {
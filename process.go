@@ -0,0 +1,39 @@
+package ddblocal
+
+import (
+	"fmt"
+	"time"
+)
+
+// stopProcessGroup asks the process group led by pid, which doesn't have to
+// be a child of the current process, to exit and kills it if it doesn't exit
+// within the grace period.
+func stopProcessGroup(pid int, gracePeriod time.Duration) error {
+	if !processAlive(pid) {
+		return nil
+	}
+	if err := interruptProcessGroup(pid); err != nil {
+		return err
+	}
+	if waitForExit(pid, gracePeriod) {
+		return nil
+	}
+	if err := killProcessGroup(pid); err != nil {
+		return err
+	}
+	waitForExit(pid, time.Second)
+	return fmt.Errorf("DynamoDB Local process did not exit within %s and was killed", gracePeriod)
+}
+
+// waitForExit waits for the process to exit for at most the given time and
+// reports whether it did.
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package ddblocal
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// tieToParent makes the kernel terminate the process started by cmd when the
+// current process dies and returns the function starting it. It must be called
+// after setProcessGroup.
+//
+// The kernel sends the signal when the thread which started the process exits
+// rather than the whole process, and the Go runtime may terminate its threads
+// at any time, so the process is started from a thread which never exits.
+func tieToParent(cmd *exec.Cmd) func() error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGTERM
+	return func() error {
+		return startOnLockedThread(cmd)
+	}
+}
+
+var (
+	lockedThreadOnce  sync.Once
+	lockedThreadStart chan func()
+)
+
+// startOnLockedThread starts cmd from a goroutine locked to its thread, which
+// never returns so that its thread is never terminated.
+func startOnLockedThread(cmd *exec.Cmd) error {
+	lockedThreadOnce.Do(func() {
+		lockedThreadStart = make(chan func())
+		go func() {
+			runtime.LockOSThread()
+			for f := range lockedThreadStart {
+				f()
+			}
+		}()
+	})
+	errc := make(chan error, 1)
+	lockedThreadStart <- func() { errc <- cmd.Start() }
+	return <-errc
+}
+
+// processCommandLine returns the command line of the process with the given
+// pid.
+func processCommandLine(pid int) (string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes.ReplaceAll(data, []byte{0}, []byte{' '}))), nil
+}
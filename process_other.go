@@ -0,0 +1,26 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package ddblocal
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// tieToParent returns cmd.Start on platforms other than Linux and Windows,
+// where the process is only cleaned up by the startup sweep of a later run.
+func tieToParent(cmd *exec.Cmd) func() error {
+	return cmd.Start
+}
+
+// processCommandLine returns the command line of the process with the given
+// pid.
+func processCommandLine(pid int) (string, error) {
+	out, err := exec.Command("ps", "-ww", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package ddblocal
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd start in a new process group so that it and all
+// of its children can be signalled at once.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcessGroup asks the process group led by pid to exit.
+func interruptProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+// killProcessGroup forcefully kills the process group led by pid.
+func killProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// isInterruptExit reports whether err describes a process which exited in
+// response to interruptProcessGroup. The JVM exits with status 143 (128 +
+// SIGTERM) when it shuts down in response to SIGTERM.
+func isInterruptExit(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return false
+	}
+	if status.Signaled() {
+		return status.Signal() == syscall.SIGTERM
+	}
+	return status.ExitStatus() == 128+int(syscall.SIGTERM)
+}
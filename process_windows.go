@@ -0,0 +1,71 @@
+package ddblocal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on Windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interruptProcessGroup kills the process pid, as Windows doesn't support
+// sending SIGTERM.
+func interruptProcessGroup(pid int) error {
+	return killProcessGroup(pid)
+}
+
+// killProcessGroup kills the process pid.
+func killProcessGroup(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	const processQueryLimitedInformation = 0x1000
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	const stillActive = 259
+	return code == stillActive
+}
+
+// tieToParent returns cmd.Start, as the process is only cleaned up by the
+// startup sweep of a later run on Windows.
+func tieToParent(cmd *exec.Cmd) func() error {
+	return cmd.Start
+}
+
+// processCommandLine returns the command line of the process with the given
+// pid.
+func processCommandLine(pid int) (string, error) {
+	query := fmt.Sprintf("(Get-CimInstance Win32_Process -Filter 'ProcessId=%d').CommandLine", pid)
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", query).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// isInterruptExit reports whether err describes a process which exited in
+// response to interruptProcessGroup. Process.Kill terminates the process with
+// exit code 1, so any other status means it crashed or exited on its own.
+func isInterruptExit(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	return exitErr.ExitCode() == 1
+}
@@ -0,0 +1,43 @@
+package ddblocal
+
+import "fmt"
+
+// ReusePolicy decides what the Emulator does when a DynamoDB compatible
+// endpoint is already running on its port.
+type ReusePolicy int
+
+const (
+	// ReuseIfPresent reuses the endpoint running on the port and only starts
+	// DynamoDB Local when there is none.
+	ReuseIfPresent ReusePolicy = iota
+	// AlwaysStartFresh always starts DynamoDB Local. When the port is taken a
+	// different free port is picked, unless the port was chosen explicitly, in
+	// which case New fails.
+	AlwaysStartFresh
+	// RequireExisting never starts DynamoDB Local and makes New fail unless a
+	// DynamoDB compatible endpoint is running on the port.
+	RequireExisting
+)
+
+func (p ReusePolicy) String() string {
+	switch p {
+	case ReuseIfPresent:
+		return "reuse-if-present"
+	case AlwaysStartFresh:
+		return "always-start-fresh"
+	case RequireExisting:
+		return "require-existing"
+	}
+	return fmt.Sprintf("ReusePolicy(%d)", int(p))
+}
+
+// ParseReusePolicy parses the name of a reuse policy as returned by its String
+// method, e.g. "require-existing".
+func ParseReusePolicy(name string) (ReusePolicy, error) {
+	for _, p := range []ReusePolicy{ReuseIfPresent, AlwaysStartFresh, RequireExisting} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid reuse policy: %q", name)
+}
@@ -0,0 +1,153 @@
+package ddblocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pidReporter is implemented by ExecutorTerminators which can report the
+// process ID of the executed process.
+type pidReporter interface {
+	Pid() int
+}
+
+// sharedInstance coordinates the use of a single DynamoDB Local instance by
+// multiple processes, e.g. the test binaries of different packages run by
+// go test ./..., through a lock file and lease files in a state directory.
+// The process which started the instance records its pid, so that whichever
+// process releases the last lease can terminate it, and so that an instance
+// left without live leases by interrupted runs can be replaced.
+type sharedInstance struct {
+	dir   string
+	lease string
+}
+
+// lock acquires the exclusive lock on the state directory, blocking until it
+// becomes available. The returned function releases the lock.
+func (s *sharedInstance) lock() (func(), error) {
+	unlock, err := lockFile(filepath.Join(s.dir, "lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", s.dir, err)
+	}
+	return unlock, nil
+}
+
+// acquire registers a lease for the calling Emulator. It must be called with
+// the lock held.
+func (s *sharedInstance) acquire() error {
+	if _, err := s.leases(); err != nil {
+		return err
+	}
+	id, err := genRandomString()
+	if err != nil {
+		return err
+	}
+	lease := filepath.Join(s.dir, fmt.Sprintf("%d-%s.lease", os.Getpid(), id))
+	if err := ioutil.WriteFile(lease, nil, 0644); err != nil {
+		return err
+	}
+	s.lease = lease
+	return nil
+}
+
+// release removes the lease of the calling Emulator and reports whether it
+// was the last one. It must be called with the lock held.
+func (s *sharedInstance) release() (bool, error) {
+	if err := os.Remove(s.lease); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	leases, err := s.leases()
+	if err != nil {
+		return false, err
+	}
+	return len(leases) == 0, nil
+}
+
+// leases returns the active leases, removing the ones held by processes which
+// no longer exist.
+func (s *sharedInstance) leases() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.lease"))
+	if err != nil {
+		return nil, err
+	}
+	var leases []string
+	for _, m := range matches {
+		pid, err := strconv.Atoi(strings.SplitN(filepath.Base(m), "-", 2)[0])
+		if err != nil || !processAlive(pid) {
+			if err := os.Remove(m); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+		leases = append(leases, m)
+	}
+	return leases, nil
+}
+
+func (s *sharedInstance) pidFile() string {
+	return filepath.Join(s.dir, "pid")
+}
+
+// recordPid records the pid and the arguments of the instance started by the
+// calling Emulator.
+func (s *sharedInstance) recordPid(pid int, args []string) error {
+	data, err := json.Marshal(pidRecord{
+		Pid:    pid,
+		Parent: os.Getpid(),
+		Args:   strings.Join(args, " "),
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.pidFile(), data, 0644)
+}
+
+// terminate stops the instance using its recorded pid, giving it the grace
+// period to exit before killing it. A process which merely reuses the pid is
+// left alone, and the pid file is kept unless the instance is known to be
+// gone.
+func (s *sharedInstance) terminate(gracePeriod time.Duration) error {
+	data, err := ioutil.ReadFile(s.pidFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var rec pidRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		_ = os.Remove(s.pidFile())
+		return fmt.Errorf("invalid pid file %s: %w", s.pidFile(), err)
+	}
+	// keep the pid file unless the instance is known to be gone
+	if err := stopRecorded(rec, gracePeriod); err != nil {
+		return err
+	}
+	return os.Remove(s.pidFile())
+}
+
+// sweep terminates the recorded instance if there are no live leases, which
+// means that the processes using it were interrupted before closing their
+// Emulators. It must be called with the lock held, before acquire.
+func (s *sharedInstance) sweep(gracePeriod time.Duration) error {
+	leases, err := s.leases()
+	if err != nil || len(leases) > 0 {
+		return err
+	}
+	return s.terminate(gracePeriod)
+}
+
+func newSharedInstance(baseDir string, port int) (*sharedInstance, error) {
+	dir := filepath.Join(baseDir, strconv.Itoa(port))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &sharedInstance{dir: dir}, nil
+}
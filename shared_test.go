@@ -0,0 +1,97 @@
+package ddblocal_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+func TestSharedInstanceTerminatedByLastOwner(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	etmA := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	ddbA, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etmA),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+
+	etmB := &mocks.ExecutorTerminatorMock{}
+	ddbB, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etmB),
+		ddblocal.CustomPresenceChecker(&mocks.PresenceCheckerMock{
+			IsPresentFunc: func(port int) bool { return true },
+		}),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+
+	equals(t, 1, len(etmA.ExecuteCalls()))
+	equals(t, 0, len(etmB.ExecuteCalls()))
+
+	ok(t, ddbB.Close())
+	equals(t, 0, len(etmA.TerminateCalls()))
+
+	ok(t, ddbA.Close())
+	equals(t, 1, len(etmA.TerminateCalls()))
+}
+
+func TestSharedInstanceIgnoresStaleLeases(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ok(t, os.MkdirAll(filepath.Join(dir, "8000"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "8000", "2147483646-stale.lease"), nil, 0644))
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+
+	ok(t, ddb.Close())
+	equals(t, 1, len(etm.TerminateCalls()))
+}
+
+func TestSharedInstanceOutlivesStartingEmulator(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(t.TempDir(), "terminated")
+	java := fakeJava(t, fmt.Sprintf(`trap 'touch %s; exit 0' TERM; echo ready; while true; do sleep 0.1; done`, marker))
+
+	var w syncBuffer
+	ddbA, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomLogWriter(&w),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+
+	ddbB, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(outputPresenceChecker(&w)),
+		ddblocal.CustomShareDir(dir),
+	)
+	ok(t, err)
+
+	ok(t, ddbA.Close())
+	_, err = os.Stat(marker)
+	assert(t, os.IsNotExist(err), "expected the instance to keep running while in use")
+
+	ok(t, ddbB.Close())
+	_, err = os.Stat(marker)
+	ok(t, err)
+}
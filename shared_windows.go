@@ -0,0 +1,8 @@
+package ddblocal
+
+import "errors"
+
+// lockFile is not supported on Windows.
+func lockFile(path string) (func(), error) {
+	return nil, errors.New("sharing an instance across processes is not supported on Windows")
+}
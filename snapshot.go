@@ -0,0 +1,160 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot stops the DynamoDB Local process, copies its database files into
+// dir, replacing its previous contents, and starts the process again. It
+// requires the database to be stored on disk (see CustomDBPath) and the
+// process to be started by the Emulator, which mustn't share it with other
+// processes (see Shared). The directory can't be inside the database
+// directory or contain it.
+func (e *Emulator) Snapshot(dir string) error {
+	if err := e.checkSnapshotDir(dir); err != nil {
+		return err
+	}
+	return e.stopped(func() error {
+		return replaceDir(e.dbPath, dir)
+	})
+}
+
+// Restore stops the DynamoDB Local process, replaces its database files with
+// the ones in dir, previously created by Snapshot, and starts the process
+// again. It has the same requirements as Snapshot.
+func (e *Emulator) Restore(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	if err := e.checkSnapshotDir(dir); err != nil {
+		return err
+	}
+	return e.stopped(func() error {
+		return replaceDir(dir, e.dbPath)
+	})
+}
+
+// checkSnapshotDir makes sure that replacing dir or the database directory
+// with a copy of the other doesn't delete the source of the copy.
+func (e *Emulator) checkSnapshotDir(dir string) error {
+	if e.dbPath == "" {
+		return nil
+	}
+	a, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	b, err := filepath.Abs(e.dbPath)
+	if err != nil {
+		return err
+	}
+	if within(a, b) || within(b, a) {
+		return fmt.Errorf("snapshot directory %s overlaps the database directory %s", dir, e.dbPath)
+	}
+	return nil
+}
+
+// within reports whether path is dir or inside of it. Both paths must be
+// absolute and clean.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// stopped calls f while the DynamoDB Local process is stopped.
+func (e *Emulator) stopped(f func() error) error {
+	if e.dbPath == "" {
+		return errors.New("snapshots require the database to be stored on disk")
+	}
+	if e.shared != nil {
+		return errors.New("snapshots are not supported for shared instances")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.owned {
+		return errors.New("snapshots require the DynamoDB Local process to be started by the Emulator")
+	}
+	if e.closing {
+		return errors.New("emulator is closed")
+	}
+
+	// make the monitor ignore the exit of the stopped process
+	e.generation++
+	if err := e.et.TerminateContext(context.Background()); err != nil {
+		return fmt.Errorf("failed to stop DynamoDB Local: %w", err)
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	if err := e.relaunch(); err != nil {
+		e.exitErr = e.withLogs(fmt.Errorf("%w (restart failed: %v)", ErrExited, err))
+		return fmt.Errorf("failed to restart DynamoDB Local: %w", err)
+	}
+	return nil
+}
+
+// replaceDir replaces dst with a copy of src. The copy is made in a temporary
+// directory next to dst which is renamed into place once it's complete, so
+// that dst is left alone if copying fails.
+func replaceDir(src, dst string) error {
+	tmp, err := ioutil.TempDir(filepath.Dir(dst), filepath.Base(dst)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	if err := copyDir(src, tmp); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// copyDir copies the regular files in the src directory tree into dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
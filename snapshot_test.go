@@ -0,0 +1,151 @@
+package ddblocal_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/ddblocal"
+	"github.com/fwojciec/ddblocal/mocks"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	t.Parallel()
+
+	dbPath := t.TempDir()
+	starts := filepath.Join(t.TempDir(), "starts")
+	ready := filepath.Join(t.TempDir(), "ready")
+	java := fakeJava(t, fmt.Sprintf(`echo start >> %[1]s; trap 'rm %[2]s; exit 0' TERM; touch %[2]s; while true; do sleep 0.1; done`, starts, ready))
+
+	ddb, err := ddblocal.New(
+		java,
+		ddblocal.CustomPresenceChecker(&mocks.PresenceCheckerMock{
+			IsPresentFunc: func(port int) bool {
+				_, err := os.Stat(ready)
+				return err == nil
+			},
+		}),
+		ddblocal.CustomDBPath(dbPath),
+	)
+	ok(t, err)
+	defer ddb.Close()
+
+	dbFile := filepath.Join(dbPath, "shared-local-instance.db")
+	ok(t, ioutil.WriteFile(dbFile, []byte("seeded"), 0644))
+
+	snapshot := filepath.Join(t.TempDir(), "snapshot")
+	ok(t, ddb.Snapshot(snapshot))
+	data, err := ioutil.ReadFile(filepath.Join(snapshot, "shared-local-instance.db"))
+	ok(t, err)
+	equals(t, "seeded", string(data))
+
+	ok(t, ioutil.WriteFile(dbFile, []byte("modified"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dbPath, "other.db"), []byte("other"), 0644))
+
+	ok(t, ddb.Restore(snapshot))
+	data, err = ioutil.ReadFile(dbFile)
+	ok(t, err)
+	equals(t, "seeded", string(data))
+	_, err = os.Stat(filepath.Join(dbPath, "other.db"))
+	assert(t, os.IsNotExist(err), "expected files not in the snapshot to be removed")
+
+	data, err = ioutil.ReadFile(starts)
+	ok(t, err)
+	equals(t, 3, strings.Count(string(data), "start"))
+	ok(t, ddb.Err())
+}
+
+func TestSnapshotRejectsOverlappingDirectories(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "db")
+	dbFile := filepath.Join(dbPath, "shared-local-instance.db")
+	ok(t, os.MkdirAll(dbPath, 0755))
+	ok(t, ioutil.WriteFile(dbFile, []byte("live"), 0644))
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomDBPath(dbPath),
+	)
+	ok(t, err)
+
+	for _, dir := range []string{dbPath, filepath.Dir(dbPath), filepath.Join(dbPath, "snapshot")} {
+		err = ddb.Snapshot(dir)
+		assert(t, err != nil, "expected an error for %s", dir)
+		equals(t, fmt.Sprintf("snapshot directory %s overlaps the database directory %s", dir, dbPath), err.Error())
+	}
+	snapshot := filepath.Join(dbPath, "snapshot")
+	ok(t, os.MkdirAll(snapshot, 0755))
+	err = ddb.Restore(snapshot)
+	assert(t, err != nil, "expected an error")
+
+	data, err := ioutil.ReadFile(dbFile)
+	ok(t, err)
+	equals(t, "live", string(data))
+	equals(t, 0, len(etm.TerminateCalls()))
+}
+
+func TestSnapshotRequiresDBPath(t *testing.T) {
+	t.Parallel()
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(&mocks.ExecutorTerminatorMock{
+			ExecuteFunc: func(name string, arg ...string) error { return nil },
+		}),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+	)
+	ok(t, err)
+
+	err = ddb.Snapshot(t.TempDir())
+	assert(t, err != nil, "expected an error")
+	equals(t, "snapshots require the database to be stored on disk", err.Error())
+}
+
+func TestSnapshotRejectsSharedInstance(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{
+		ExecuteFunc:   func(name string, arg ...string) error { return nil },
+		TerminateFunc: func() error { return nil },
+	}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(startingPresenceChecker()),
+		ddblocal.CustomDBPath(t.TempDir()),
+		ddblocal.CustomShareDir(t.TempDir()),
+	)
+	ok(t, err)
+	defer ddb.Close()
+
+	err = ddb.Snapshot(t.TempDir())
+	assert(t, err != nil, "expected an error")
+	equals(t, "snapshots are not supported for shared instances", err.Error())
+	equals(t, 0, len(etm.TerminateCalls()))
+}
+
+func TestSnapshotRequiresOwnedProcess(t *testing.T) {
+	t.Parallel()
+
+	etm := &mocks.ExecutorTerminatorMock{}
+	ddb, err := ddblocal.New(
+		ddblocal.CustomExecutorTerminator(etm),
+		ddblocal.CustomPresenceChecker(&mocks.PresenceCheckerMock{
+			IsPresentFunc: func(port int) bool { return true },
+		}),
+		ddblocal.CustomDBPath(t.TempDir()),
+	)
+	ok(t, err)
+
+	err = ddb.Snapshot(t.TempDir())
+	assert(t, err != nil, "expected an error")
+	equals(t, "snapshots require the DynamoDB Local process to be started by the Emulator", err.Error())
+	equals(t, 0, len(etm.TerminateCalls()))
+}
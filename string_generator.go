@@ -10,15 +10,23 @@ func (r randStringGenFunc) Generate() (string, error) {
 
 func genRandomString() (string, error) {
 	const letters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-	bytes := make([]byte, 12)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		return "", err
+	// bytes at or above the largest multiple of len(letters) are rejected,
+	// as mapping them would favour the first letters
+	const limit = 256 - 256%len(letters)
+	res := make([]byte, 0, 12)
+	buf := make([]byte, 16)
+	for len(res) < cap(res) {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit || len(res) == cap(res) {
+				continue
+			}
+			res = append(res, letters[int(b)%len(letters)])
+		}
 	}
-	for i, b := range bytes {
-		bytes[i] = letters[b%byte(len(letters))]
-	}
-	return string(bytes), nil
+	return string(res), nil
 }
 
 func NewStringGenerator() StringGenerator {
@@ -1,6 +1,7 @@
 package ddblocal_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/fwojciec/ddblocal"
@@ -23,3 +24,20 @@ func TestGeneratesUniqueStrings(t *testing.T) {
 	ok(t, err)
 	assert(t, res1 != res2, "expected generated strings to be different")
 }
+
+func TestGeneratesStringsOfAllowedCharacters(t *testing.T) {
+	t.Parallel()
+	const letters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	sg := ddblocal.NewStringGenerator()
+	seen := make(map[rune]bool)
+	for i := 0; i < 100; i++ {
+		res, err := sg.Generate()
+		ok(t, err)
+		for _, r := range res {
+			assert(t, strings.ContainsRune(letters, r), "unexpected character: %q", r)
+			seen[r] = true
+		}
+	}
+	// 1200 characters include every letter with overwhelming probability
+	equals(t, len(letters), len(seen))
+}
@@ -0,0 +1,240 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	defaultTableTimeout = 30 * time.Second
+
+	tablePollInterval    = 20 * time.Millisecond
+	tableMaxPollInterval = time.Second
+
+	minTableNameLen = 3
+	maxTableNameLen = 255
+
+	// maxTableNamePrefixLen leaves room for the random suffix in the longest
+	// table name.
+	maxTableNamePrefixLen = 200
+)
+
+// ready reports whether tests can be run against the Emulator, skipping or
+// failing the test if they can't.
+func (e *Emulator) ready(t testing.TB) bool {
+	if err := e.unavailable; err != nil {
+		t.Skipf("%v", err)
+		return false
+	}
+	if err := e.Err(); err != nil {
+		t.Fatalf("%v", err)
+		return false
+	}
+	return true
+}
+
+// createTable creates a randomly named table from a copy of tableDef, deletes
+// it when the test completes and returns its name.
+func (e *Emulator) createTable(t testing.TB, tableDef *dynamodb.CreateTableInput) string {
+	tableName, err := e.tableName(t.Name())
+	if err != nil {
+		t.Fatalf("failed to generate table name: %v", err)
+	}
+
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	out, err := e.client.CreateTableWithContext(ctx, normalizeTableDef(tableDef, tableName))
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := testContext(t)
+		defer cancel()
+		if t.Failed() && e.keepFailedTables {
+			e.keepTable(ctx, t, tableName)
+			return
+		}
+		// errors rather than fatal errors, so that they don't stop other
+		// cleanup functions or mask the failure of the test
+		if err := e.deleteTable(ctx, tableName); err != nil {
+			if exitErr := e.Err(); exitErr != nil {
+				err = exitErr
+			}
+			t.Errorf("failed to delete table %s: %v", tableName, err)
+		}
+	})
+
+	if out == nil || !isActive(out.TableDescription) {
+		if err := e.waitForTable(ctx, tableName, "become ACTIVE", isActive); err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+	}
+
+	return tableName
+}
+
+// tableName generates a table name. Without a table name prefix it is the
+// random string itself, otherwise it's the prefix, the sanitized test name and
+// the random string joined with dashes, with the test name truncated to keep
+// the result within the table name length limit.
+func (e *Emulator) tableName(testName string) (string, error) {
+	suffix, err := e.tng.Generate()
+	if err != nil {
+		return "", err
+	}
+	if e.tableNamePrefix == "" {
+		return suffix, nil
+	}
+
+	suffix = sanitizeTableName(suffix)
+	testName = sanitizeTableName(testName)
+	maxLen := maxTableNameLen - len(e.tableNamePrefix) - len(suffix) - 2
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	if len(testName) > maxLen {
+		testName = strings.TrimRight(testName[:maxLen], "_")
+	}
+	var parts []string
+	for _, part := range []string{e.tableNamePrefix, testName, suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	name := strings.Join(parts, "-")
+	if len(name) < minTableNameLen || len(name) > maxTableNameLen {
+		return "", fmt.Errorf("invalid table name: %q", name)
+	}
+	return name, nil
+}
+
+// sanitizeTableName replaces every run of characters which aren't allowed in
+// table names (anything but a-z, A-Z, 0-9, '_', '-' and '.') with a single
+// underscore.
+func sanitizeTableName(s string) string {
+	var b strings.Builder
+	replaced := false
+	for _, r := range s {
+		if isTableNameChar(r) {
+			b.WriteRune(r)
+			replaced = false
+			continue
+		}
+		if !replaced {
+			b.WriteByte('_')
+			replaced = true
+		}
+	}
+	return b.String()
+}
+
+func isTableNameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '_' || r == '-' || r == '.'
+}
+
+// validTableNamePrefix reports whether prefix can start a table name.
+func validTableNamePrefix(prefix string) bool {
+	return len(prefix) <= maxTableNamePrefixLen && sanitizeTableName(prefix) == prefix
+}
+
+// deleteTable deletes the table and waits until it is gone.
+func (e *Emulator) deleteTable(ctx context.Context, tableName string) error {
+	if _, err := e.client.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{
+		TableName: aws.String(tableName),
+	}); err != nil {
+		return err
+	}
+	return e.waitForTable(ctx, tableName, "disappear", func(table *dynamodb.TableDescription) bool {
+		return table == nil
+	})
+}
+
+// isActive reports whether the table and all of its global secondary indexes
+// are ACTIVE.
+func isActive(table *dynamodb.TableDescription) bool {
+	if table == nil || aws.StringValue(table.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForTable polls the description of the table with an exponential
+// backoff until cond, which is passed nil once the table doesn't exist,
+// reports true or the table timeout expires.
+func (e *Emulator) waitForTable(ctx context.Context, tableName, what string, cond func(*dynamodb.TableDescription) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, e.tableTimeout)
+	defer cancel()
+
+	interval := tablePollInterval
+	for {
+		out, err := e.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		var table *dynamodb.TableDescription
+		var aerr awserr.Error
+		switch {
+		case err == nil:
+			table = out.Table
+		case errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException:
+		case ctx.Err() != nil:
+		default:
+			return err
+		}
+		if ctx.Err() == nil && cond(table) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("table %s did not %s within %s: %w", tableName, what, e.tableTimeout, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > tableMaxPollInterval {
+			interval = tableMaxPollInterval
+		}
+	}
+}
+
+// normalizeTableDef returns a deep copy of tableDef with the given name and
+// the provisioned throughput of the table and its global secondary indexes
+// set, unless already specified.
+func normalizeTableDef(tableDef *dynamodb.CreateTableInput, tableName string) *dynamodb.CreateTableInput {
+	def := awsutil.CopyOf(tableDef).(*dynamodb.CreateTableInput)
+	def.TableName = aws.String(tableName)
+
+	if def.ProvisionedThroughput == nil {
+		def.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(1),
+			WriteCapacityUnits: aws.Int64(1),
+		}
+	}
+
+	for _, gsi := range def.GlobalSecondaryIndexes {
+		if gsi.ProvisionedThroughput != nil {
+			continue
+		}
+		gsi.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(1),
+			WriteCapacityUnits: aws.Int64(1),
+		}
+	}
+	return def
+}
@@ -0,0 +1,70 @@
+package ddblocal
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// ErrUnavailable is matched by the errors returned by New when DynamoDB Local
+// can't be provided in the current environment: there is no Java runtime, no
+// DynamoDB Local distribution, no container CLI or no running endpoint to
+// connect to.
+var ErrUnavailable = errors.New("DynamoDB Local is unavailable")
+
+// unavailableError marks an error as caused by DynamoDB Local being
+// unavailable.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// unavailable marks err as caused by DynamoDB Local being unavailable if it
+// is one of the errors reporting a missing prerequisite.
+func unavailable(err error) error {
+	for _, target := range []error{ErrJavaNotFound, ErrJarNotFound, ErrLibNotFound, exec.ErrNotFound} {
+		if errors.Is(err, target) {
+			return &unavailableError{err}
+		}
+	}
+	return err
+}
+
+// SkipIfUnavailable makes New return an Emulator whose Runner skips the tests
+// instead of an error matching ErrUnavailable, so that the tests can still be
+// run by contributors who can't run DynamoDB Local. It can also be enabled
+// with DDBLOCAL_SKIP_UNAVAILABLE=true or skip_unavailable in the config file.
+func SkipIfUnavailable() EmulatorOption {
+	return func(e *Emulator) {
+		e.skipUnavailable = true
+	}
+}
+
+// Unavailable returns the reason the tests run by the Emulator are skipped
+// when it was created with the SkipIfUnavailable option and DynamoDB Local
+// was unavailable, and nil otherwise.
+func (e *Emulator) Unavailable() error {
+	return e.unavailable
+}
+
+// failed handles an error encountered while providing DynamoDB Local,
+// returning the Emulator in the unavailable state if it should skip tests
+// instead.
+func (e *Emulator) failed(err error) (*Emulator, error) {
+	err = unavailable(err)
+	if e.skipUnavailable && errors.Is(err, ErrUnavailable) {
+		e.unavailable = err
+		return e, nil
+	}
+	return nil, err
+}
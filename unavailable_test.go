@@ -0,0 +1,119 @@
+package ddblocal_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fwojciec/ddblocal"
+)
+
+func TestInitReportsUnavailable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options func(t *testing.T) []ddblocal.EmulatorOption
+	}{
+		{
+			name: "java missing",
+			options: func(t *testing.T) []ddblocal.EmulatorOption {
+				return []ddblocal.EmulatorOption{
+					ddblocal.CustomPresenceChecker(neverPresentChecker()),
+					ddblocal.CustomJavaPath(filepath.Join(t.TempDir(), "java")),
+					fakeDistribution(t),
+				}
+			},
+		},
+		{
+			name: "jar missing",
+			options: func(t *testing.T) []ddblocal.EmulatorOption {
+				return []ddblocal.EmulatorOption{
+					ddblocal.CustomPresenceChecker(neverPresentChecker()),
+					fakeJava(t, "exit 1"),
+					ddblocal.CustomJarPath(filepath.Join(t.TempDir(), "DynamoDBLocal.jar")),
+				}
+			},
+		},
+		{
+			name: "container CLI missing",
+			options: func(t *testing.T) []ddblocal.EmulatorOption {
+				return []ddblocal.EmulatorOption{
+					ddblocal.CustomPresenceChecker(neverPresentChecker()),
+					ddblocal.ContainerBackend(),
+					ddblocal.CustomContainerCLI("ddblocal-missing-container-cli"),
+				}
+			},
+		},
+		{
+			name: "endpoint missing",
+			options: func(t *testing.T) []ddblocal.EmulatorOption {
+				return []ddblocal.EmulatorOption{
+					ddblocal.CustomPresenceChecker(neverPresentChecker()),
+					ddblocal.CustomReusePolicy(ddblocal.RequireExisting),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ddblocal.New(tt.options(t)...)
+			assert(t, errors.Is(err, ddblocal.ErrUnavailable), "expected ErrUnavailable, got %v", err)
+		})
+	}
+}
+
+func TestInitDoesNotReportOtherErrorsAsUnavailable(t *testing.T) {
+	t.Parallel()
+
+	home := fakeJavaHome(t, `java version "1.8.0_292"`, "exit 1")
+	_, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		ddblocal.CustomJavaPath(filepath.Join(home, "bin", "java")),
+		fakeDistribution(t),
+		ddblocal.SkipIfUnavailable(),
+	)
+	assert(t, err != nil, "expected an error")
+	assert(t, !errors.Is(err, ddblocal.ErrUnavailable), "unexpected ErrUnavailable")
+}
+
+func TestRunnerSkipsWhenUnavailable(t *testing.T) {
+	t.Parallel()
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		ddblocal.CustomReusePolicy(ddblocal.RequireExisting),
+		ddblocal.SkipIfUnavailable(),
+	)
+	ok(t, err)
+	assert(t, errors.Is(ddb.Unavailable(), ddblocal.ErrUnavailable), "expected ErrUnavailable, got %v", ddb.Unavailable())
+
+	var subtest *testing.T
+	var called bool
+	t.Run("subtest", func(t *testing.T) {
+		subtest = t
+		ddb.Runner(t, &dynamodb.CreateTableInput{}, func(dynamodbiface.DynamoDBAPI, string) {
+			called = true
+		})
+	})
+	assert(t, subtest.Skipped(), "expected the test to be skipped")
+	assert(t, !called, "expected the test body not to be called")
+	ok(t, ddb.Close())
+}
+
+func TestSkipIfUnavailableFromEnvironment(t *testing.T) {
+	setenv(t, "DDBLOCAL_SKIP_UNAVAILABLE", "true")
+
+	ddb, err := ddblocal.New(
+		ddblocal.CustomPresenceChecker(neverPresentChecker()),
+		ddblocal.CustomReusePolicy(ddblocal.RequireExisting),
+	)
+	ok(t, err)
+	assert(t, errors.Is(ddb.Unavailable(), ddblocal.ErrUnavailable), "expected ErrUnavailable, got %v", ddb.Unavailable())
+	equals(t, true, *ddb.Config().SkipUnavailable)
+	ok(t, ddb.Close())
+}